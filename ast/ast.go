@@ -9,9 +9,12 @@ import (
 // Every Node in the ast implements the Node interface
 // The TokenLiteral returns the LiteralValue of the associated token
 // TokenLiteral() is used for debugging
+// Pos() and End() return the span of source the node was parsed from
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // The position of the first character of the node
+	End() token.Position // The position just after the last character of the node
 }
 
 // Statements Nodes do not produce any values
@@ -31,8 +34,10 @@ type Expression interface {
 // Every go program is a series of Statements
 // The Program struct implements the Node interface
 // The Program Node is the root of the AST
+// Comments holds the comments of the source. They are not part of the tree: Walk, Equal and Hash ignore them
 type Program struct {
 	Statements []Statement
+	Comments   []token.Token // The token.COMMENT tokens in source order
 }
 
 // Returns the LiteralValue of the token of the first Statement
@@ -55,6 +60,7 @@ func (p *Program) String() string {
 type LetStatement struct {
 	Token token.Token // The token.LET token
 	Name  *Identifier // The identidier name
+	Type  TypeExpr    // The type annotation, nil if there is none
 	Value Expression  // The expression
 }
 
@@ -68,6 +74,9 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.Value)
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -77,9 +86,11 @@ func (ls *LetStatement) String() string {
 }
 
 // The identifier contains the token.IDENT token and the name value
+// A function parameter may have a type annotation
 type Identifier struct {
 	Token token.Token // The token.IDENT token
 	Value string      // The name
+	Type  TypeExpr    // The type annotation of a parameter, nil if there is none
 }
 
 func (id *Identifier) TokenLiteral() string {
@@ -89,6 +100,9 @@ func (id *Identifier) TokenLiteral() string {
 func (id *Identifier) expressionNode() {}
 
 func (id *Identifier) String() string {
+	if id.Type != nil {
+		return id.Value + ": " + id.Type.String()
+	}
 	return id.Value
 }
 
@@ -105,7 +119,10 @@ func (rs *ReturnStatement) TokenLiteral() string {
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(rs.TokenLiteral() + " ")
-	out.WriteString(rs.ReturnValue.String())
+	if rs.ReturnValue != nil {
+		out.WriteString(rs.ReturnValue.String())
+	}
+	out.WriteString(";")
 	return out.String()
 }
 
@@ -121,7 +138,7 @@ func (es *ExpressionStatement) TokenLiteral() string {
 
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
-		return es.Expression.String()
+		return es.Expression.String() + ";"
 	}
 	return ""
 }
@@ -209,13 +226,13 @@ func (ie *IfExpression) TokenLiteral() string {
 }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
-	out.WriteString("if")
+	out.WriteString("if (")
 	out.WriteString(ie.Condition.String())
-	out.WriteString(" ")
+	out.WriteString(") ")
 	out.WriteString(ie.Consequence.String())
 
 	if ie.Alternative != nil {
-		out.WriteString("else")
+		out.WriteString(" else ")
 		out.WriteString(ie.Alternative.String())
 	}
 	return out.String()
@@ -224,6 +241,7 @@ func (ie *IfExpression) String() string {
 type BlockStatement struct {
 	Token      token.Token // the '{' token
 	Statements []Statement
+	Rbrace     token.Position // the position of the '}' token
 }
 
 func (bs *BlockStatement) statementNode() {}
@@ -233,15 +251,19 @@ func (bs *BlockStatement) TokenLiteral() string {
 
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
+	out.WriteString("{ ")
 	for _, stmt := range bs.Statements {
 		out.WriteString(stmt.String())
+		out.WriteString(" ")
 	}
+	out.WriteString("}")
 	return out.String()
 }
 
 type FunctionLiteral struct {
 	Token      token.Token // the 'fn' token
 	Parameters []*Identifier
+	ReturnType TypeExpr // The annotated result type, nil if there is none
 	Body       *BlockStatement
 }
 
@@ -260,6 +282,127 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
+
+// MacroLiteral is a macro such as macro(a, b) { quote(unquote(a) + unquote(b)) }
+// Its body is not evaluated: the macro package expands the calls of the macros bound by let
+type MacroLiteral struct {
+	Token      token.Token // the 'macro' token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode() {}
+func (ml *MacroLiteral) TokenLiteral() string {
+	return ml.Token.Literal
+}
+func (ml *MacroLiteral) String() string {
+	params := make([]string, len(ml.Parameters))
+	for i, pr := range ml.Parameters {
+		params[i] = pr.String()
+	}
+	return ml.TokenLiteral() + "(" + strings.Join(params, ", ") + ") " + ml.Body.String()
+}
+
+type CallExpression struct {
+	Token     token.Token // the '(' token
+	Function  Expression  // Identifier or FunctionLiteral
+	Arguments []Expression
+	Rparen    token.Position // the position of the ')' token
+}
+
+func (ce *CallExpression) expressionNode() {}
+func (ce *CallExpression) TokenLiteral() string {
+	return ce.Token.Literal
+}
+func (ce *CallExpression) String() string {
+	var out bytes.Buffer
+
+	args := []string{}
+	for _, a := range ce.Arguments {
+		args = append(args, a.String())
+	}
+	out.WriteString(ce.Function.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(")")
+	return out.String()
+}
+
+// TypeExpr Nodes are type annotations
+// typeExprNode function is just for debugging
+type TypeExpr interface {
+	Node
+	typeExprNode()
+}
+
+// NamedType is a type name such as int, with type arguments for a generic type such as map[string, int]
+type NamedType struct {
+	Token    token.Token // The token.IDENT token of the name
+	Name     string
+	Args     []TypeExpr
+	Rbracket token.Position // The position of the ']' token, not valid without arguments
+}
+
+func (nt *NamedType) typeExprNode() {}
+func (nt *NamedType) TokenLiteral() string {
+	return nt.Token.Literal
+}
+func (nt *NamedType) String() string {
+	if len(nt.Args) == 0 {
+		return nt.Name
+	}
+	return nt.Name + "[" + joinTypes(nt.Args) + "]"
+}
+
+// ListType is the type of a list such as [int]
+type ListType struct {
+	Token    token.Token // The '[' token
+	Elem     TypeExpr
+	Rbracket token.Position // The position of the ']' token
+}
+
+func (lt *ListType) typeExprNode() {}
+func (lt *ListType) TokenLiteral() string {
+	return lt.Token.Literal
+}
+func (lt *ListType) String() string {
+	if lt.Elem == nil {
+		return "[]"
+	}
+	return "[" + lt.Elem.String() + "]"
+}
+
+// FunctionType is the type of a function such as fn(int, bool) -> int
+type FunctionType struct {
+	Token  token.Token // The 'fn' token
+	Params []TypeExpr
+	Rparen token.Position // The position of the ')' token
+	Result TypeExpr
+}
+
+func (ft *FunctionType) typeExprNode() {}
+func (ft *FunctionType) TokenLiteral() string {
+	return ft.Token.Literal
+}
+func (ft *FunctionType) String() string {
+	out := "fn(" + joinTypes(ft.Params) + ")"
+	if ft.Result != nil {
+		out += " -> " + ft.Result.String()
+	}
+	return out
+}
+
+// joinTypes is a helper function that joins the sources of types with commas
+func joinTypes(types []TypeExpr) string {
+	strs := make([]string, len(types))
+	for i, t := range types {
+		strs[i] = t.String()
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,164 @@
+package ast
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+// Clone returns a deep copy of the tree rooted at node
+// The copy shares nothing with the original, so either can be modified freely
+func Clone(node Node) Node {
+	if node == nil {
+		return nil
+	}
+	switch n := node.(type) {
+	case *Program:
+		c := &Program{Statements: cloneStatements(n.Statements)}
+		if n.Comments != nil {
+			c.Comments = append([]token.Token{}, n.Comments...)
+		}
+		return c
+	case *LetStatement:
+		c := *n
+		c.Name = cloneIdentifier(n.Name)
+		c.Type = cloneType(n.Type)
+		c.Value = cloneExpression(n.Value)
+		return &c
+	case *ReturnStatement:
+		c := *n
+		c.ReturnValue = cloneExpression(n.ReturnValue)
+		return &c
+	case *ExpressionStatement:
+		c := *n
+		c.Expression = cloneExpression(n.Expression)
+		return &c
+	case *BlockStatement:
+		return cloneBlock(n)
+	case *Identifier:
+		return cloneIdentifier(n)
+	case *IntegerLiteral:
+		c := *n
+		return &c
+	case *Boolean:
+		c := *n
+		return &c
+	case *PrefixExpression:
+		c := *n
+		c.Right = cloneExpression(n.Right)
+		return &c
+	case *InfixExpression:
+		c := *n
+		c.Left = cloneExpression(n.Left)
+		c.Right = cloneExpression(n.Right)
+		return &c
+	case *IfExpression:
+		c := *n
+		c.Condition = cloneExpression(n.Condition)
+		c.Consequence = cloneBlock(n.Consequence)
+		c.Alternative = cloneBlock(n.Alternative)
+		return &c
+	case *FunctionLiteral:
+		c := *n
+		if n.Parameters != nil {
+			c.Parameters = make([]*Identifier, len(n.Parameters))
+			for i, pr := range n.Parameters {
+				c.Parameters[i] = cloneIdentifier(pr)
+			}
+		}
+		c.ReturnType = cloneType(n.ReturnType)
+		c.Body = cloneBlock(n.Body)
+		return &c
+	case *MacroLiteral:
+		c := *n
+		if n.Parameters != nil {
+			c.Parameters = make([]*Identifier, len(n.Parameters))
+			for i, pr := range n.Parameters {
+				c.Parameters[i] = cloneIdentifier(pr)
+			}
+		}
+		c.Body = cloneBlock(n.Body)
+		return &c
+	case *CallExpression:
+		c := *n
+		c.Function = cloneExpression(n.Function)
+		if n.Arguments != nil {
+			c.Arguments = make([]Expression, len(n.Arguments))
+			for i, arg := range n.Arguments {
+				c.Arguments[i] = cloneExpression(arg)
+			}
+		}
+		return &c
+	case *NamedType:
+		c := *n
+		c.Args = cloneTypes(n.Args)
+		return &c
+	case *ListType:
+		c := *n
+		c.Elem = cloneType(n.Elem)
+		return &c
+	case *FunctionType:
+		c := *n
+		c.Params = cloneTypes(n.Params)
+		c.Result = cloneType(n.Result)
+		return &c
+	}
+	panic(fmt.Sprintf("ast.Clone: unexpected node type %T", node))
+}
+
+// cloneStatements is a helper function that clones a list of statements
+func cloneStatements(stmts []Statement) []Statement {
+	if stmts == nil {
+		return nil
+	}
+	c := make([]Statement, len(stmts))
+	for i, stmt := range stmts {
+		c[i] = Clone(stmt).(Statement)
+	}
+	return c
+}
+
+// cloneExpression is a helper function that clones an expression that may be missing
+func cloneExpression(expr Expression) Expression {
+	if expr == nil {
+		return nil
+	}
+	return Clone(expr).(Expression)
+}
+
+func cloneIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	c := *ident
+	c.Type = cloneType(ident.Type)
+	return &c
+}
+
+// cloneType is a helper function that clones a type expression that may be missing
+func cloneType(t TypeExpr) TypeExpr {
+	if t == nil {
+		return nil
+	}
+	return Clone(t).(TypeExpr)
+}
+
+// cloneTypes is a helper function that clones a list of type expressions
+func cloneTypes(types []TypeExpr) []TypeExpr {
+	if types == nil {
+		return nil
+	}
+	c := make([]TypeExpr, len(types))
+	for i, t := range types {
+		c[i] = cloneType(t)
+	}
+	return c
+}
+
+func cloneBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	c := *block
+	c.Statements = cloneStatements(block.Statements)
+	return &c
+}
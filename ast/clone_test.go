@@ -0,0 +1,91 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"reflect"
+	"testing"
+)
+
+// nodes returns the nodes of the tree rooted at node in the order of Walk
+func nodes(node ast.Node) []ast.Node {
+	var list []ast.Node
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			list = append(list, n)
+		}
+		return true
+	})
+	return list
+}
+
+func TestClone(t *testing.T) {
+	for _, src := range sources {
+		program := parse(t, src)
+		comments := append(program.Comments[:0:0], program.Comments...)
+
+		clone := ast.Clone(program).(*ast.Program)
+		if !ast.Equal(program, clone, 0) || !reflect.DeepEqual(program.Comments, clone.Comments) {
+			t.Fatalf("%q: the clone %q differs", src, clone.String())
+		}
+
+		// No node is shared
+		original := map[ast.Node]bool{}
+		for _, n := range nodes(program) {
+			original[n] = true
+		}
+		for _, n := range nodes(clone) {
+			if original[n] {
+				t.Errorf("%q: the clone shares the %T %s", src, n, n)
+			}
+		}
+
+		// Changing every node of the clone leaves the original as it was
+		for _, n := range nodes(clone) {
+			switch n := n.(type) {
+			case *ast.Program:
+				n.Statements[0] = &ast.ReturnStatement{}
+				for i := range n.Comments {
+					n.Comments[i].Literal = "// changed"
+				}
+			case *ast.BlockStatement:
+				if len(n.Statements) > 0 {
+					n.Statements[0] = &ast.ReturnStatement{}
+				}
+			case *ast.Identifier:
+				n.Value = "changed"
+				n.Token.Literal = "changed"
+			case *ast.IntegerLiteral:
+				n.Value++
+			case *ast.InfixExpression:
+				n.Operator = "-"
+			case *ast.FunctionLiteral:
+				if len(n.Parameters) > 0 {
+					n.Parameters[0] = &ast.Identifier{Value: "changed"}
+				}
+			case *ast.MacroLiteral:
+				n.Parameters[0] = &ast.Identifier{Value: "changed"}
+			case *ast.CallExpression:
+				if len(n.Arguments) > 0 {
+					n.Arguments[0] = &ast.Boolean{}
+				}
+			case *ast.NamedType:
+				n.Name = "changed"
+				if len(n.Args) > 0 {
+					n.Args[0] = &ast.NamedType{Name: "changed"}
+				}
+			case *ast.FunctionType:
+				n.Params[0] = &ast.NamedType{Name: "changed"}
+			}
+		}
+		if !ast.Equal(program, parse(t, src), 0) {
+			t.Errorf("%q: changing the clone changed the original to %q", src, program.String())
+		}
+		if !reflect.DeepEqual(program.Comments, comments) {
+			t.Errorf("%q: changing the clone changed the comments to %v", src, program.Comments)
+		}
+	}
+
+	if got := ast.Clone(nil); got != nil {
+		t.Errorf("Clone(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,142 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Dot writes the tree rooted at node as a Graphviz graph
+// Every node is labelled with its type and its operator, name or value, and every edge with
+// the field of the parent that holds the child, eg. "left" or "statements[1]"
+func Dot(w io.Writer, node Node) error {
+	d := &dotWriter{w: w}
+	d.printf("digraph ast {\n")
+	d.printf("\tnode [shape=box, fontname=\"monospace\"];\n")
+	d.node(node)
+	d.printf("}\n")
+	return d.err
+}
+
+type dotWriter struct {
+	w    io.Writer
+	next int // The id of the next node
+	err  error
+}
+
+func (d *dotWriter) printf(format string, args ...interface{}) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+// node writes node and its subtree and returns the id of node
+func (d *dotWriter) node(node Node) int {
+	id := d.next
+	d.next++
+	d.printf("\tn%d [label=%s];\n", id, strconv.Quote(dotLabel(node)))
+	for _, c := range children(node) {
+		child := d.node(c.node)
+		d.printf("\tn%d -> n%d [label=%s];\n", id, child, strconv.Quote(c.field))
+	}
+	return id
+}
+
+// dotLabel is a helper function that returns the label of node
+func dotLabel(node Node) string {
+	kind := kindOf(node)
+	switch n := node.(type) {
+	case *Identifier:
+		return kind + "\n" + n.Value
+	case *IntegerLiteral:
+		return kind + "\n" + strconv.FormatInt(n.Value, 10)
+	case *Boolean:
+		return kind + "\n" + strconv.FormatBool(n.Value)
+	case *PrefixExpression:
+		return kind + "\n" + n.Operator
+	case *InfixExpression:
+		return kind + "\n" + n.Operator
+	case *NamedType:
+		return kind + "\n" + n.Name
+	}
+	return kind
+}
+
+// child is a child node and the name of the field that holds it
+type child struct {
+	field string
+	node  Node
+}
+
+// children returns the children of node in the order documented on Walk, named by field
+// Elements of lists are named by the field and their index
+func children(node Node) []child {
+	var cs []child
+	add := func(field string, n Node) {
+		if !isNil(n) {
+			cs = append(cs, child{field, n})
+		}
+	}
+	switch n := node.(type) {
+	case *Program:
+		for i, stmt := range n.Statements {
+			add(indexed("statements", i), stmt)
+		}
+	case *LetStatement:
+		add("name", n.Name)
+		add("type", n.Type)
+		add("value", n.Value)
+	case *ReturnStatement:
+		add("returnValue", n.ReturnValue)
+	case *ExpressionStatement:
+		add("expression", n.Expression)
+	case *BlockStatement:
+		for i, stmt := range n.Statements {
+			add(indexed("statements", i), stmt)
+		}
+	case *PrefixExpression:
+		add("right", n.Right)
+	case *InfixExpression:
+		add("left", n.Left)
+		add("right", n.Right)
+	case *IfExpression:
+		add("condition", n.Condition)
+		add("consequence", n.Consequence)
+		add("alternative", n.Alternative)
+	case *FunctionLiteral:
+		for i, pr := range n.Parameters {
+			add(indexed("parameters", i), pr)
+		}
+		add("returnType", n.ReturnType)
+		add("body", n.Body)
+	case *MacroLiteral:
+		for i, pr := range n.Parameters {
+			add(indexed("parameters", i), pr)
+		}
+		add("body", n.Body)
+	case *CallExpression:
+		add("function", n.Function)
+		for i, arg := range n.Arguments {
+			add(indexed("arguments", i), arg)
+		}
+	case *Identifier:
+		add("type", n.Type)
+	case *NamedType:
+		for i, t := range n.Args {
+			add(indexed("args", i), t)
+		}
+	case *ListType:
+		add("elem", n.Elem)
+	case *FunctionType:
+		for i, t := range n.Params {
+			add(indexed("params", i), t)
+		}
+		add("result", n.Result)
+	}
+	return cs
+}
+
+// indexed is a helper function that names an element of a list field
+func indexed(field string, i int) string {
+	return fmt.Sprintf("%s[%d]", field, i)
+}
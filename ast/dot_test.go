@@ -0,0 +1,56 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"strings"
+	"testing"
+)
+
+func TestDot(t *testing.T) {
+	var b strings.Builder
+	if err := ast.Dot(&b, parse(t, "let x = -a + f(1, true);")); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph ast {
+	node [shape=box, fontname="monospace"];
+	n0 [label="Program"];
+	n1 [label="LetStatement"];
+	n2 [label="Identifier\nx"];
+	n1 -> n2 [label="name"];
+	n3 [label="InfixExpression\n+"];
+	n4 [label="PrefixExpression\n-"];
+	n5 [label="Identifier\na"];
+	n4 -> n5 [label="right"];
+	n3 -> n4 [label="left"];
+	n6 [label="CallExpression"];
+	n7 [label="Identifier\nf"];
+	n6 -> n7 [label="function"];
+	n8 [label="IntegerLiteral\n1"];
+	n6 -> n8 [label="arguments[0]"];
+	n9 [label="Boolean\ntrue"];
+	n6 -> n9 [label="arguments[1]"];
+	n3 -> n6 [label="right"];
+	n1 -> n3 [label="value"];
+	n0 -> n1 [label="statements[0]"];
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%swant\n%s", got, want)
+	}
+}
+
+func TestDotHasEveryNode(t *testing.T) {
+	for _, src := range sources {
+		program := parse(t, src)
+		var b strings.Builder
+		if err := ast.Dot(&b, program); err != nil {
+			t.Fatal(err)
+		}
+		// A tree has an edge to every node but the root
+		edges := strings.Count(b.String(), " -> ")
+		labelled := strings.Count(b.String(), "[label=") - edges
+		if n := len(nodes(program)); labelled != n || edges != n-1 {
+			t.Errorf("%q: the graph has %d nodes and %d edges, want %d and %d", src, labelled, edges, n, n-1)
+		}
+	}
+}
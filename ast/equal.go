@@ -0,0 +1,152 @@
+package ast
+
+import (
+	"monkey/token"
+	"reflect"
+)
+
+// CompareMode controls what Equal compares
+type CompareMode uint
+
+const (
+	IgnorePositions CompareMode = 1 << iota // Ignore the source positions of tokens and nodes
+)
+
+// Equal reports whether the trees rooted at a and b are structurally equal
+// Two nodes are equal if they have the same type, equal tokens and fields, and equal children
+// Tokens are compared by type, literal and, unless mode has IgnorePositions, position
+// A nil list of children equals an empty one. The comments of a Program are not compared
+func Equal(a, b Node, mode CompareMode) bool {
+	c := comparer{mode: mode}
+	return c.equal(a, b)
+}
+
+type comparer struct {
+	mode CompareMode
+}
+
+func (c comparer) equal(a, b Node) bool {
+	if isNil(a) || isNil(b) {
+		return isNil(a) && isNil(b)
+	}
+	switch a := a.(type) {
+	case *Program:
+		b, ok := b.(*Program)
+		return ok && c.statements(a.Statements, b.Statements)
+	case *LetStatement:
+		b, ok := b.(*LetStatement)
+		return ok && c.token(a.Token, b.Token) && c.equal(a.Name, b.Name) && c.equal(a.Type, b.Type) && c.equal(a.Value, b.Value)
+	case *ReturnStatement:
+		b, ok := b.(*ReturnStatement)
+		return ok && c.token(a.Token, b.Token) && c.equal(a.ReturnValue, b.ReturnValue)
+	case *ExpressionStatement:
+		b, ok := b.(*ExpressionStatement)
+		return ok && c.token(a.Token, b.Token) && c.equal(a.Expression, b.Expression)
+	case *BlockStatement:
+		b, ok := b.(*BlockStatement)
+		return ok && c.token(a.Token, b.Token) && c.position(a.Rbrace, b.Rbrace) && c.statements(a.Statements, b.Statements)
+	case *Identifier:
+		b, ok := b.(*Identifier)
+		return ok && c.token(a.Token, b.Token) && a.Value == b.Value && c.equal(a.Type, b.Type)
+	case *IntegerLiteral:
+		b, ok := b.(*IntegerLiteral)
+		return ok && c.token(a.Token, b.Token) && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && c.token(a.Token, b.Token) && a.Value == b.Value
+	case *PrefixExpression:
+		b, ok := b.(*PrefixExpression)
+		return ok && c.token(a.Token, b.Token) && a.Operator == b.Operator && c.equal(a.Right, b.Right)
+	case *InfixExpression:
+		b, ok := b.(*InfixExpression)
+		return ok && c.token(a.Token, b.Token) && a.Operator == b.Operator && c.equal(a.Left, b.Left) && c.equal(a.Right, b.Right)
+	case *IfExpression:
+		b, ok := b.(*IfExpression)
+		return ok && c.token(a.Token, b.Token) && c.equal(a.Condition, b.Condition) &&
+			c.equal(a.Consequence, b.Consequence) && c.equal(a.Alternative, b.Alternative)
+	case *FunctionLiteral:
+		b, ok := b.(*FunctionLiteral)
+		if !ok || !c.token(a.Token, b.Token) || len(a.Parameters) != len(b.Parameters) {
+			return false
+		}
+		for i := range a.Parameters {
+			if !c.equal(a.Parameters[i], b.Parameters[i]) {
+				return false
+			}
+		}
+		return c.equal(a.ReturnType, b.ReturnType) && c.equal(a.Body, b.Body)
+	case *MacroLiteral:
+		b, ok := b.(*MacroLiteral)
+		if !ok || !c.token(a.Token, b.Token) || len(a.Parameters) != len(b.Parameters) {
+			return false
+		}
+		for i := range a.Parameters {
+			if !c.equal(a.Parameters[i], b.Parameters[i]) {
+				return false
+			}
+		}
+		return c.equal(a.Body, b.Body)
+	case *CallExpression:
+		b, ok := b.(*CallExpression)
+		if !ok || !c.token(a.Token, b.Token) || !c.position(a.Rparen, b.Rparen) || len(a.Arguments) != len(b.Arguments) {
+			return false
+		}
+		for i := range a.Arguments {
+			if !c.equal(a.Arguments[i], b.Arguments[i]) {
+				return false
+			}
+		}
+		return c.equal(a.Function, b.Function)
+	case *NamedType:
+		b, ok := b.(*NamedType)
+		return ok && c.token(a.Token, b.Token) && a.Name == b.Name && c.position(a.Rbracket, b.Rbracket) && c.types(a.Args, b.Args)
+	case *ListType:
+		b, ok := b.(*ListType)
+		return ok && c.token(a.Token, b.Token) && c.position(a.Rbracket, b.Rbracket) && c.equal(a.Elem, b.Elem)
+	case *FunctionType:
+		b, ok := b.(*FunctionType)
+		return ok && c.token(a.Token, b.Token) && c.position(a.Rparen, b.Rparen) && c.types(a.Params, b.Params) && c.equal(a.Result, b.Result)
+	}
+	return false
+}
+
+func (c comparer) types(a, b []TypeExpr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !c.equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c comparer) statements(a, b []Statement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !c.equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c comparer) token(a, b token.Token) bool {
+	return a.Type == b.Type && a.Literal == b.Literal && c.position(a.Pos, b.Pos)
+}
+
+func (c comparer) position(a, b token.Position) bool {
+	return c.mode&IgnorePositions != 0 || a == b
+}
+
+// isNil is a helper function that checks if node is nil or a nil pointer stored in the interface
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
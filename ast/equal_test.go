@@ -0,0 +1,115 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"testing"
+)
+
+func TestEqualAndHash(t *testing.T) {
+	tests := []struct {
+		a, b string
+		mode ast.CompareMode
+		want bool
+	}{
+		{"let x = 1 + 2;", "let x = 1 + 2;", 0, true},
+		// Positions count unless they are ignored; comments never count
+		{"let x = 1 + 2;", "let x =\n\t1 + 2;", 0, false},
+		{"let x = 1 + 2;", "let x =\n\t1 + 2;", ast.IgnorePositions, true},
+		{"let x = 1 + 2; // c", "// d\nlet x = 1 + 2;", ast.IgnorePositions, true},
+		{"f(fn(a) { if (a) { 1 } });", "f( fn(a) { if (a) { 1 }; } );", ast.IgnorePositions, true},
+		// Parentheses only group
+		{"(1 + 2) * 3;", "((1 + 2)) * (3);", ast.IgnorePositions, true},
+		{"1 + 2 * 3;", "(1 + 2) * 3;", ast.IgnorePositions, false},
+		{"let x = 1;", "let y = 1;", ast.IgnorePositions, false},
+		{"let x: int = 1;", "let x = 1;", ast.IgnorePositions, false},
+		{"fn(a: [int]) { a };", "fn(a: [bool]) { a };", ast.IgnorePositions, false},
+		{"fn(a, b) { a };", "fn(a) { a };", ast.IgnorePositions, false},
+		{"f(1, 2);", "f(1);", ast.IgnorePositions, false},
+		{"if (x) { 1 };", "if (x) { 1 } else { };", ast.IgnorePositions, false},
+		{"true;", "false;", ast.IgnorePositions, false},
+		{"-1;", "!1;", ast.IgnorePositions, false},
+		{"macro(a) { a };", "fn(a) { a };", ast.IgnorePositions, false},
+	}
+	for _, tt := range tests {
+		a, b := parse(t, tt.a), parse(t, tt.b)
+		if got := ast.Equal(a, b, tt.mode); got != tt.want {
+			t.Errorf("Equal(%q, %q, %d) = %t, want %t", tt.a, tt.b, tt.mode, got, tt.want)
+		}
+		if got := ast.Equal(b, a, tt.mode); got != tt.want {
+			t.Errorf("Equal(%q, %q, %d) = %t, want %t", tt.b, tt.a, tt.mode, got, tt.want)
+		}
+		// Trees that are equal without their positions hash the same. The unequal trees here
+		// also happen to hash differently
+		if equal := ast.Equal(a, b, ast.IgnorePositions); equal != (ast.Hash(a) == ast.Hash(b)) {
+			t.Errorf("%q and %q: Equal is %t, but the hashes are %x and %x", tt.a, tt.b, equal, ast.Hash(a), ast.Hash(b))
+		}
+	}
+}
+
+func TestHashIsStructural(t *testing.T) {
+	// Every node of a tree hashes the same as the same node of a copy with other positions
+	a := parse(t, sources[1])
+	b := parse(t, "\n\n"+sources[1])
+	var nodes []ast.Node
+	ast.Inspect(a, func(n ast.Node) bool {
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+		return true
+	})
+	i := 0
+	ast.Inspect(b, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		if ast.Hash(n) != ast.Hash(nodes[i]) || !ast.Equal(n, nodes[i], ast.IgnorePositions) {
+			t.Errorf("%T %s does not match its copy", n, n)
+		}
+		i++
+		return true
+	})
+}
+
+// nilNodes are nil pointers of every node type
+var nilNodes = []ast.Node{
+	(*ast.Program)(nil), (*ast.LetStatement)(nil), (*ast.ReturnStatement)(nil), (*ast.ExpressionStatement)(nil),
+	(*ast.BlockStatement)(nil), (*ast.Identifier)(nil), (*ast.IntegerLiteral)(nil), (*ast.Boolean)(nil),
+	(*ast.PrefixExpression)(nil), (*ast.InfixExpression)(nil), (*ast.IfExpression)(nil), (*ast.FunctionLiteral)(nil),
+	(*ast.MacroLiteral)(nil), (*ast.CallExpression)(nil), (*ast.NamedType)(nil), (*ast.ListType)(nil),
+	(*ast.FunctionType)(nil),
+}
+
+func TestEqualNil(t *testing.T) {
+	for _, n := range nilNodes {
+		if !ast.Equal(n, nil, 0) || !ast.Equal(nil, n, 0) {
+			t.Errorf("a nil %T does not equal nil", n)
+		}
+		if ast.Hash(n) != ast.Hash(nil) {
+			t.Errorf("a nil %T does not hash as nil", n)
+		}
+		if ast.Equal(n, parse(t, "1;"), 0) {
+			t.Errorf("a nil %T equals a program", n)
+		}
+	}
+
+	// A missing child is the same whether it is nil or a nil pointer
+	tests := []struct {
+		a, b ast.Node
+	}{
+		{&ast.ReturnStatement{}, &ast.ReturnStatement{ReturnValue: (*ast.InfixExpression)(nil)}},
+		{&ast.ExpressionStatement{}, &ast.ExpressionStatement{Expression: (*ast.CallExpression)(nil)}},
+		{&ast.PrefixExpression{}, &ast.PrefixExpression{Right: (*ast.IntegerLiteral)(nil)}},
+		{&ast.LetStatement{}, &ast.LetStatement{Type: (*ast.NamedType)(nil), Value: (*ast.Boolean)(nil)}},
+		{&ast.Identifier{}, &ast.Identifier{Type: (*ast.FunctionType)(nil)}},
+		{&ast.FunctionLiteral{}, &ast.FunctionLiteral{ReturnType: (*ast.ListType)(nil), Body: (*ast.BlockStatement)(nil)}},
+		{&ast.Program{}, &ast.Program{Statements: []ast.Statement{}}},
+	}
+	for _, tt := range tests {
+		if !ast.Equal(tt.a, tt.b, 0) {
+			t.Errorf("%#v does not equal %#v", tt.a, tt.b)
+		}
+		if ast.Hash(tt.a) != ast.Hash(tt.b) {
+			t.Errorf("%#v and %#v hash differently", tt.a, tt.b)
+		}
+	}
+}
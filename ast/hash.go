@@ -0,0 +1,164 @@
+package ast
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"monkey/token"
+)
+
+// Hash returns a structural hash of the tree rooted at node
+// Positions and comments are not part of the hash, so trees that are Equal with IgnorePositions have the same hash
+// The hash only depends on the tree, never on the process, so it can be stored and compared across runs
+func Hash(node Node) uint64 {
+	h := hasher{fnv.New64a()}
+	h.node(node)
+	return h.Sum64()
+}
+
+type hasher struct {
+	hash.Hash64
+}
+
+func (h hasher) node(node Node) {
+	if isNil(node) {
+		h.string("nil")
+		return
+	}
+	switch n := node.(type) {
+	case *Program:
+		h.string("Program")
+		h.statements(n.Statements)
+	case *LetStatement:
+		h.string("LetStatement")
+		h.token(n.Token)
+		h.node(n.Name)
+		h.optional(n.Type)
+		h.node(n.Value)
+	case *ReturnStatement:
+		h.string("ReturnStatement")
+		h.token(n.Token)
+		h.node(n.ReturnValue)
+	case *ExpressionStatement:
+		h.string("ExpressionStatement")
+		h.token(n.Token)
+		h.node(n.Expression)
+	case *BlockStatement:
+		h.string("BlockStatement")
+		h.token(n.Token)
+		h.statements(n.Statements)
+	case *Identifier:
+		h.string("Identifier")
+		h.token(n.Token)
+		h.string(n.Value)
+		h.optional(n.Type)
+	case *IntegerLiteral:
+		h.string("IntegerLiteral")
+		h.token(n.Token)
+		h.int(n.Value)
+	case *Boolean:
+		h.string("Boolean")
+		h.token(n.Token)
+		h.string(fmt.Sprint(n.Value))
+	case *PrefixExpression:
+		h.string("PrefixExpression")
+		h.token(n.Token)
+		h.string(n.Operator)
+		h.node(n.Right)
+	case *InfixExpression:
+		h.string("InfixExpression")
+		h.token(n.Token)
+		h.string(n.Operator)
+		h.node(n.Left)
+		h.node(n.Right)
+	case *IfExpression:
+		h.string("IfExpression")
+		h.token(n.Token)
+		h.node(n.Condition)
+		h.node(n.Consequence)
+		h.node(n.Alternative)
+	case *FunctionLiteral:
+		h.string("FunctionLiteral")
+		h.token(n.Token)
+		h.int(int64(len(n.Parameters)))
+		for _, pr := range n.Parameters {
+			h.node(pr)
+		}
+		h.optional(n.ReturnType)
+		h.node(n.Body)
+	case *MacroLiteral:
+		h.string("MacroLiteral")
+		h.token(n.Token)
+		h.int(int64(len(n.Parameters)))
+		for _, pr := range n.Parameters {
+			h.node(pr)
+		}
+		h.node(n.Body)
+	case *CallExpression:
+		h.string("CallExpression")
+		h.token(n.Token)
+		h.node(n.Function)
+		h.int(int64(len(n.Arguments)))
+		for _, arg := range n.Arguments {
+			h.node(arg)
+		}
+	case *NamedType:
+		h.string("NamedType")
+		h.token(n.Token)
+		h.string(n.Name)
+		h.types(n.Args)
+	case *ListType:
+		h.string("ListType")
+		h.token(n.Token)
+		h.node(n.Elem)
+	case *FunctionType:
+		h.string("FunctionType")
+		h.token(n.Token)
+		h.types(n.Params)
+		h.node(n.Result)
+	default:
+		panic(fmt.Sprintf("ast.Hash: unexpected node type %T", node))
+	}
+}
+
+func (h hasher) statements(stmts []Statement) {
+	h.int(int64(len(stmts)))
+	for _, stmt := range stmts {
+		h.node(stmt)
+	}
+}
+
+// optional hashes a type annotation that may be missing
+// Nothing is written for a missing annotation, and a present one is marked before it is hashed,
+// so an annotation is never mistaken for the node that follows it
+func (h hasher) optional(t TypeExpr) {
+	if !isNil(t) {
+		h.string("type")
+		h.node(t)
+	}
+}
+
+func (h hasher) types(types []TypeExpr) {
+	h.int(int64(len(types)))
+	for _, t := range types {
+		h.node(t)
+	}
+}
+
+func (h hasher) token(tok token.Token) {
+	h.string(string(tok.Type))
+	h.string(tok.Literal)
+}
+
+// string writes s prefixed with its length, so that "ab","c" and "a","bc" hash differently
+func (h hasher) string(s string) {
+	h.int(int64(len(s)))
+	h.Write([]byte(s))
+}
+
+func (h hasher) int(i int64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(i))
+	h.Write(buf[:])
+}
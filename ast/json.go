@@ -0,0 +1,547 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"monkey/token"
+	"strings"
+)
+
+// The JSON encoding of the ast
+//
+// Every node is an object with the fields
+//
+//	kind     the name of the node type eg. "InfixExpression"
+//	span     the start and end positions of the node
+//	token    the token stored in the node. A Program has none
+//	children the child nodes keyed by field name. Lists of nodes are arrays
+//
+// and, depending on the kind, the fields
+//
+//	operator the operator of a PrefixExpression or InfixExpression
+//	value    the name of an Identifier or NamedType, the value of an IntegerLiteral or Boolean
+//	rbrace   the position of the '}' of a BlockStatement
+//	rparen   the position of the ')' of a CallExpression or FunctionType
+//	rbracket the position of the ']' of a NamedType with arguments or a ListType
+//	comments the comment tokens of a Program
+//
+// Missing children are left out of the children object. Fields are only ever added to the format, never renamed
+
+// JSONSpan is the span of a node in the JSON encoding
+type JSONSpan struct {
+	Start token.Position `json:"start"`
+	End   token.Position `json:"end"`
+}
+
+// JSONNode is a node in the JSON encoding
+type JSONNode struct {
+	Kind     string                     `json:"kind"`
+	Span     JSONSpan                   `json:"span"`
+	Token    *token.Token               `json:"token,omitempty"`
+	Operator string                     `json:"operator,omitempty"`
+	Value    json.RawMessage            `json:"value,omitempty"`
+	Rbrace   *token.Position            `json:"rbrace,omitempty"`
+	Rparen   *token.Position            `json:"rparen,omitempty"`
+	Rbracket *token.Position            `json:"rbracket,omitempty"`
+	Comments []token.Token              `json:"comments,omitempty"`
+	Children map[string]json.RawMessage `json:"children,omitempty"`
+}
+
+// ToJSON encodes the tree rooted at node
+func ToJSON(node Node) ([]byte, error) {
+	return json.Marshal(toJSONNode(node))
+}
+
+// toJSONNode converts a node and its children to their JSON form
+func toJSONNode(node Node) *JSONNode {
+	jn := &JSONNode{Span: JSONSpan{Start: node.Pos(), End: node.End()}, Children: map[string]json.RawMessage{}}
+	switch node := node.(type) {
+	case *Program:
+		jn.Kind = "Program"
+		jn.Comments = node.Comments
+		jn.Children["statements"] = encodeStatements(node.Statements)
+	case *LetStatement:
+		jn.Kind = "LetStatement"
+		jn.Token = tokenOf(node.Token)
+		if node.Name != nil {
+			jn.Children["name"] = encodeChild(node.Name)
+		}
+		if node.Type != nil {
+			jn.Children["type"] = encodeChild(node.Type)
+		}
+		if node.Value != nil {
+			jn.Children["value"] = encodeChild(node.Value)
+		}
+	case *ReturnStatement:
+		jn.Kind = "ReturnStatement"
+		jn.Token = tokenOf(node.Token)
+		if node.ReturnValue != nil {
+			jn.Children["returnValue"] = encodeChild(node.ReturnValue)
+		}
+	case *ExpressionStatement:
+		jn.Kind = "ExpressionStatement"
+		jn.Token = tokenOf(node.Token)
+		if node.Expression != nil {
+			jn.Children["expression"] = encodeChild(node.Expression)
+		}
+	case *BlockStatement:
+		jn.Kind = "BlockStatement"
+		jn.Token = tokenOf(node.Token)
+		if node.Rbrace.IsValid() {
+			rbrace := node.Rbrace
+			jn.Rbrace = &rbrace
+		}
+		jn.Children["statements"] = encodeStatements(node.Statements)
+	case *Identifier:
+		jn.Kind = "Identifier"
+		jn.Token = tokenOf(node.Token)
+		jn.Value = encodeValue(node.Value)
+		if node.Type != nil {
+			jn.Children["type"] = encodeChild(node.Type)
+		}
+	case *IntegerLiteral:
+		jn.Kind = "IntegerLiteral"
+		jn.Token = tokenOf(node.Token)
+		jn.Value = encodeValue(node.Value)
+	case *Boolean:
+		jn.Kind = "Boolean"
+		jn.Token = tokenOf(node.Token)
+		jn.Value = encodeValue(node.Value)
+	case *PrefixExpression:
+		jn.Kind = "PrefixExpression"
+		jn.Token = tokenOf(node.Token)
+		jn.Operator = node.Operator
+		if node.Right != nil {
+			jn.Children["right"] = encodeChild(node.Right)
+		}
+	case *InfixExpression:
+		jn.Kind = "InfixExpression"
+		jn.Token = tokenOf(node.Token)
+		jn.Operator = node.Operator
+		if node.Left != nil {
+			jn.Children["left"] = encodeChild(node.Left)
+		}
+		if node.Right != nil {
+			jn.Children["right"] = encodeChild(node.Right)
+		}
+	case *IfExpression:
+		jn.Kind = "IfExpression"
+		jn.Token = tokenOf(node.Token)
+		if node.Condition != nil {
+			jn.Children["condition"] = encodeChild(node.Condition)
+		}
+		if node.Consequence != nil {
+			jn.Children["consequence"] = encodeChild(node.Consequence)
+		}
+		if node.Alternative != nil {
+			jn.Children["alternative"] = encodeChild(node.Alternative)
+		}
+	case *FunctionLiteral:
+		jn.Kind = "FunctionLiteral"
+		jn.Token = tokenOf(node.Token)
+		params := make([]*JSONNode, 0, len(node.Parameters))
+		for _, pr := range node.Parameters {
+			params = append(params, toJSONNode(pr))
+		}
+		jn.Children["parameters"] = mustMarshal(params)
+		if node.ReturnType != nil {
+			jn.Children["returnType"] = encodeChild(node.ReturnType)
+		}
+		if node.Body != nil {
+			jn.Children["body"] = encodeChild(node.Body)
+		}
+	case *MacroLiteral:
+		jn.Kind = "MacroLiteral"
+		jn.Token = tokenOf(node.Token)
+		params := make([]*JSONNode, 0, len(node.Parameters))
+		for _, pr := range node.Parameters {
+			params = append(params, toJSONNode(pr))
+		}
+		jn.Children["parameters"] = mustMarshal(params)
+		if node.Body != nil {
+			jn.Children["body"] = encodeChild(node.Body)
+		}
+	case *CallExpression:
+		jn.Kind = "CallExpression"
+		jn.Token = tokenOf(node.Token)
+		if node.Rparen.IsValid() {
+			rparen := node.Rparen
+			jn.Rparen = &rparen
+		}
+		if node.Function != nil {
+			jn.Children["function"] = encodeChild(node.Function)
+		}
+		args := make([]*JSONNode, 0, len(node.Arguments))
+		for _, arg := range node.Arguments {
+			args = append(args, toJSONNode(arg))
+		}
+		jn.Children["arguments"] = mustMarshal(args)
+	case *NamedType:
+		jn.Kind = "NamedType"
+		jn.Token = tokenOf(node.Token)
+		jn.Value = encodeValue(node.Name)
+		jn.Rbracket = positionOf(node.Rbracket)
+		if len(node.Args) > 0 {
+			jn.Children["args"] = encodeTypes(node.Args)
+		}
+	case *ListType:
+		jn.Kind = "ListType"
+		jn.Token = tokenOf(node.Token)
+		jn.Rbracket = positionOf(node.Rbracket)
+		if node.Elem != nil {
+			jn.Children["elem"] = encodeChild(node.Elem)
+		}
+	case *FunctionType:
+		jn.Kind = "FunctionType"
+		jn.Token = tokenOf(node.Token)
+		jn.Rparen = positionOf(node.Rparen)
+		jn.Children["params"] = encodeTypes(node.Params)
+		if node.Result != nil {
+			jn.Children["result"] = encodeChild(node.Result)
+		}
+	default:
+		panic(fmt.Sprintf("ast: unexpected node type %T", node))
+	}
+	if len(jn.Children) == 0 {
+		jn.Children = nil
+	}
+	return jn
+}
+
+// tokenOf is a helper function that returns a pointer to a copy of tok
+func tokenOf(tok token.Token) *token.Token {
+	return &tok
+}
+
+// positionOf is a helper function that returns a pointer to a copy of pos, or nil if pos is not valid
+func positionOf(pos token.Position) *token.Position {
+	if !pos.IsValid() {
+		return nil
+	}
+	return &pos
+}
+
+// encodeChild is a helper function that encodes a single child node
+func encodeChild(node Node) json.RawMessage {
+	return mustMarshal(toJSONNode(node))
+}
+
+// encodeStatements is a helper function that encodes a list of statements
+func encodeStatements(stmts []Statement) json.RawMessage {
+	nodes := make([]*JSONNode, 0, len(stmts))
+	for _, stmt := range stmts {
+		nodes = append(nodes, toJSONNode(stmt))
+	}
+	return mustMarshal(nodes)
+}
+
+// encodeTypes is a helper function that encodes a list of type expressions
+func encodeTypes(types []TypeExpr) json.RawMessage {
+	nodes := make([]*JSONNode, 0, len(types))
+	for _, t := range types {
+		nodes = append(nodes, toJSONNode(t))
+	}
+	return mustMarshal(nodes)
+}
+
+// encodeValue is a helper function that encodes the value of a literal
+func encodeValue(v interface{}) json.RawMessage {
+	return mustMarshal(v)
+}
+
+// mustMarshal is a helper function for values that always encode, such as the JSONNode types
+func mustMarshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// FromJSON decodes a tree encoded by ToJSON
+func FromJSON(data []byte) (Node, error) {
+	var jn JSONNode
+	if err := json.Unmarshal(data, &jn); err != nil {
+		return nil, err
+	}
+	return fromJSONNode(&jn)
+}
+
+// ProgramFromJSON decodes a Program encoded by ToJSON
+func ProgramFromJSON(data []byte) (*Program, error) {
+	node, err := FromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	program, ok := node.(*Program)
+	if !ok {
+		return nil, fmt.Errorf("ast: expected a Program, got %s", kindOf(node))
+	}
+	return program, nil
+}
+
+// fromJSONNode rebuilds a node and its children from their JSON form
+func fromJSONNode(jn *JSONNode) (Node, error) {
+	d := decoder{jn: jn}
+	if jn.Token == nil && jn.Kind != "Program" {
+		return nil, fmt.Errorf("ast: %s has no token", jn.Kind)
+	}
+	switch jn.Kind {
+	case "Program":
+		return &Program{Statements: d.statements("statements"), Comments: jn.Comments}, d.err
+	case "LetStatement":
+		stmt := &LetStatement{Token: *jn.Token}
+		stmt.Name = d.identifier("name")
+		stmt.Type = d.typeExpr("type")
+		stmt.Value = d.expression("value")
+		return stmt, d.err
+	case "ReturnStatement":
+		return &ReturnStatement{Token: *jn.Token, ReturnValue: d.expression("returnValue")}, d.err
+	case "ExpressionStatement":
+		return &ExpressionStatement{Token: *jn.Token, Expression: d.expression("expression")}, d.err
+	case "BlockStatement":
+		block := &BlockStatement{Token: *jn.Token, Statements: d.statements("statements")}
+		if jn.Rbrace != nil {
+			block.Rbrace = *jn.Rbrace
+		}
+		return block, d.err
+	case "Identifier":
+		ident := &Identifier{Token: *jn.Token}
+		d.value(&ident.Value)
+		ident.Type = d.typeExpr("type")
+		return ident, d.err
+	case "IntegerLiteral":
+		lit := &IntegerLiteral{Token: *jn.Token}
+		d.value(&lit.Value)
+		return lit, d.err
+	case "Boolean":
+		b := &Boolean{Token: *jn.Token}
+		d.value(&b.Value)
+		return b, d.err
+	case "PrefixExpression":
+		return &PrefixExpression{Token: *jn.Token, Operator: jn.Operator, Right: d.expression("right")}, d.err
+	case "InfixExpression":
+		expr := &InfixExpression{Token: *jn.Token, Operator: jn.Operator}
+		expr.Left = d.expression("left")
+		expr.Right = d.expression("right")
+		return expr, d.err
+	case "IfExpression":
+		expr := &IfExpression{Token: *jn.Token}
+		expr.Condition = d.expression("condition")
+		expr.Consequence = d.block("consequence")
+		expr.Alternative = d.block("alternative")
+		return expr, d.err
+	case "FunctionLiteral":
+		lit := &FunctionLiteral{Token: *jn.Token, Parameters: []*Identifier{}}
+		for _, node := range d.list("parameters") {
+			ident, ok := node.(*Identifier)
+			if !ok {
+				d.fail("parameters", node)
+				break
+			}
+			lit.Parameters = append(lit.Parameters, ident)
+		}
+		lit.ReturnType = d.typeExpr("returnType")
+		lit.Body = d.block("body")
+		return lit, d.err
+	case "MacroLiteral":
+		lit := &MacroLiteral{Token: *jn.Token, Parameters: []*Identifier{}}
+		for _, node := range d.list("parameters") {
+			ident, ok := node.(*Identifier)
+			if !ok {
+				d.fail("parameters", node)
+				break
+			}
+			lit.Parameters = append(lit.Parameters, ident)
+		}
+		lit.Body = d.block("body")
+		return lit, d.err
+	case "CallExpression":
+		expr := &CallExpression{Token: *jn.Token, Arguments: []Expression{}}
+		if jn.Rparen != nil {
+			expr.Rparen = *jn.Rparen
+		}
+		expr.Function = d.expression("function")
+		for _, node := range d.list("arguments") {
+			arg, ok := node.(Expression)
+			if !ok {
+				d.fail("arguments", node)
+				break
+			}
+			expr.Arguments = append(expr.Arguments, arg)
+		}
+		return expr, d.err
+	case "NamedType":
+		t := &NamedType{Token: *jn.Token}
+		d.value(&t.Name)
+		if jn.Rbracket != nil {
+			t.Rbracket = *jn.Rbracket
+		}
+		if _, ok := jn.Children["args"]; ok {
+			t.Args = d.types("args")
+		}
+		return t, d.err
+	case "ListType":
+		t := &ListType{Token: *jn.Token, Elem: d.typeExpr("elem")}
+		if jn.Rbracket != nil {
+			t.Rbracket = *jn.Rbracket
+		}
+		return t, d.err
+	case "FunctionType":
+		t := &FunctionType{Token: *jn.Token, Params: d.types("params")}
+		if jn.Rparen != nil {
+			t.Rparen = *jn.Rparen
+		}
+		t.Result = d.typeExpr("result")
+		return t, d.err
+	}
+	return nil, fmt.Errorf("ast: unknown node kind %q", jn.Kind)
+}
+
+// decoder decodes the children of a single JSONNode
+// The first error is kept in err and every later call does nothing
+type decoder struct {
+	jn  *JSONNode
+	err error
+}
+
+// child decodes the child stored under field, or returns nil if there is none
+func (d *decoder) child(field string) Node {
+	raw, ok := d.jn.Children[field]
+	if !ok || d.err != nil {
+		return nil
+	}
+	node, err := FromJSON(raw)
+	if err != nil {
+		d.err = err
+		return nil
+	}
+	return node
+}
+
+// list decodes the list of children stored under field
+func (d *decoder) list(field string) []Node {
+	raw, ok := d.jn.Children[field]
+	if !ok || d.err != nil {
+		return nil
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(raw, &raws); err != nil {
+		d.err = err
+		return nil
+	}
+	nodes := []Node{}
+	for _, r := range raws {
+		node, err := FromJSON(r)
+		if err != nil {
+			d.err = err
+			return nil
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func (d *decoder) statements(field string) []Statement {
+	stmts := []Statement{}
+	for _, node := range d.list(field) {
+		stmt, ok := node.(Statement)
+		if !ok {
+			d.fail(field, node)
+			return stmts
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
+
+func (d *decoder) expression(field string) Expression {
+	node := d.child(field)
+	if node == nil {
+		return nil
+	}
+	expr, ok := node.(Expression)
+	if !ok {
+		d.fail(field, node)
+		return nil
+	}
+	return expr
+}
+
+func (d *decoder) typeExpr(field string) TypeExpr {
+	node := d.child(field)
+	if node == nil {
+		return nil
+	}
+	t, ok := node.(TypeExpr)
+	if !ok {
+		d.fail(field, node)
+		return nil
+	}
+	return t
+}
+
+func (d *decoder) types(field string) []TypeExpr {
+	types := []TypeExpr{}
+	for _, node := range d.list(field) {
+		t, ok := node.(TypeExpr)
+		if !ok {
+			d.fail(field, node)
+			return types
+		}
+		types = append(types, t)
+	}
+	return types
+}
+
+func (d *decoder) identifier(field string) *Identifier {
+	node := d.child(field)
+	if node == nil {
+		return nil
+	}
+	ident, ok := node.(*Identifier)
+	if !ok {
+		d.fail(field, node)
+		return nil
+	}
+	return ident
+}
+
+func (d *decoder) block(field string) *BlockStatement {
+	node := d.child(field)
+	if node == nil {
+		return nil
+	}
+	block, ok := node.(*BlockStatement)
+	if !ok {
+		d.fail(field, node)
+		return nil
+	}
+	return block
+}
+
+// value decodes the value field into v
+func (d *decoder) value(v interface{}) {
+	if d.err != nil {
+		return
+	}
+	if len(d.jn.Value) == 0 {
+		d.err = fmt.Errorf("ast: %s has no value", d.jn.Kind)
+		return
+	}
+	if err := json.Unmarshal(d.jn.Value, v); err != nil {
+		d.err = fmt.Errorf("ast: bad value for %s: %s", d.jn.Kind, err)
+	}
+}
+
+// fail records that node is not allowed in field
+func (d *decoder) fail(field string, node Node) {
+	if d.err == nil {
+		d.err = fmt.Errorf("ast: %s.%s cannot hold a %s", d.jn.Kind, field, kindOf(node))
+	}
+}
+
+// kindOf is a helper function that returns the kind used for node in the JSON encoding
+func kindOf(node Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
@@ -0,0 +1,142 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/parser"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// sources are programs that use every node type between them
+var sources = []string{
+	`let x = 5; // five
+let y: int = -x + 2 * (3 - 1) / 4;
+return !true == false;`,
+	`// An adder
+let add = fn(a: int, b) -> int {
+	if (a < b) { return a + b; } else { b + a }
+};
+add(1, add(2, 3));`,
+	`let apply = fn(f: fn(int, bool) -> [int], m: map[int, [a]]) { f(1, true) };
+let m = macro(c, body) { quote(if (unquote(c)) { unquote(body) }) };
+if (x != 1) { };
+fn() { }();`,
+}
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	program, err := parser.ParseFile("", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	return program
+}
+
+// kinds returns the sorted names of the node types in the tree rooted at node
+func kinds(node ast.Node) []string {
+	seen := map[string]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			seen[strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")] = true
+		}
+		return true
+	})
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSourcesCoverEveryNodeType(t *testing.T) {
+	program := &ast.Program{}
+	for _, src := range sources {
+		program.Statements = append(program.Statements, parse(t, src).Statements...)
+	}
+	want := []string{
+		"BlockStatement", "Boolean", "CallExpression", "ExpressionStatement", "FunctionLiteral", "FunctionType",
+		"Identifier", "IfExpression", "InfixExpression", "IntegerLiteral", "LetStatement", "ListType",
+		"MacroLiteral", "NamedType", "PrefixExpression", "Program", "ReturnStatement",
+	}
+	if got := kinds(program); !reflect.DeepEqual(got, want) {
+		t.Errorf("the sources have the node types\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, src := range sources {
+		program := parse(t, src)
+		data, err := ast.ToJSON(program)
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		decoded, err := ast.ProgramFromJSON(data)
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		if !ast.Equal(program, decoded, 0) {
+			t.Errorf("%q: decoded to %q", src, decoded.String())
+		}
+		if !reflect.DeepEqual(program.Comments, decoded.Comments) {
+			t.Errorf("%q: the comments decoded to %v, want %v", src, decoded.Comments, program.Comments)
+		}
+
+		// Every node also round-trips on its own
+		ast.Inspect(program, func(n ast.Node) bool {
+			if n == nil {
+				return false
+			}
+			data, err := ast.ToJSON(n)
+			if err != nil {
+				t.Fatalf("%s: %v", n, err)
+			}
+			decoded, err := ast.FromJSON(data)
+			if err != nil {
+				t.Errorf("%T %s: %v", n, n, err)
+			} else if !ast.Equal(n, decoded, 0) {
+				t.Errorf("%T %s decoded to %s", n, n, decoded)
+			}
+			return true
+		})
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	const tok = `"token":{"type":"IDENT","literal":"x","pos":{"offset":0,"line":1,"column":1}}`
+	// want is the start of the error, as the wording of the errors of encoding/json depends on the Go version
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`{"kind":"Program"`, ""},
+		{`[]`, ""},
+		{`{"kind":"Program","children":{"statements":{}}}`, ""},
+		{`{"kind":"Loop",` + tok + `}`, `ast: unknown node kind "Loop"`},
+		{`{"kind":"Identifier"}`, "ast: Identifier has no token"},
+		{`{"kind":"Identifier",` + tok + `}`, "ast: Identifier has no value"},
+		{`{"kind":"IntegerLiteral",` + tok + `,"value":"x"}`, "ast: bad value for IntegerLiteral: "},
+		// A child of the wrong kind, also deep in the tree
+		{`{"kind":"Program","children":{"statements":[{"kind":"Identifier",` + tok + `,"value":"x"}]}}`, "ast: Program.statements cannot hold a Identifier"},
+		{`{"kind":"PrefixExpression",` + tok + `,"children":{"right":{"kind":"ReturnStatement",` + tok + `}}}`, "ast: PrefixExpression.right cannot hold a ReturnStatement"},
+		{`{"kind":"FunctionLiteral",` + tok + `,"children":{"parameters":[{"kind":"Boolean",` + tok + `,"value":true}]}}`, "ast: FunctionLiteral.parameters cannot hold a Boolean"},
+		{`{"kind":"LetStatement",` + tok + `,"children":{"value":{"kind":"Identifier"}}}`, "ast: Identifier has no token"},
+	}
+	for _, tt := range tests {
+		node, err := ast.FromJSON([]byte(tt.json))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("FromJSON(%s) = %v, %v; want the error %q", tt.json, node, err, tt.want)
+		}
+	}
+
+	data, err := ast.ToJSON(parse(t, "x;").Statements[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ast.ProgramFromJSON(data); err == nil || err.Error() != "ast: expected a Program, got ExpressionStatement" {
+		t.Errorf("ProgramFromJSON of a statement: got %v, want an error", err)
+	}
+}
@@ -0,0 +1,190 @@
+package ast
+
+import "monkey/token"
+
+// The Pos and End methods of every node
+// Optional children that are missing are skipped, so a partially parsed node still has a span
+
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Type != nil {
+		return ls.Type.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End()
+}
+
+func (id *Identifier) Pos() token.Position { return id.Token.Pos }
+func (id *Identifier) End() token.Position {
+	if id.Type != nil {
+		return id.Type.End()
+	}
+	return id.Token.End()
+}
+
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End()
+}
+
+// The token of an expression statement is its first token, which may be a '(' that is not part of the expression
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End()
+}
+
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position { return il.Token.End() }
+
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End()
+}
+
+func (ie *InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+
+func (ie *InfixExpression) End() token.Position {
+	if ie.Right != nil {
+		return ie.Right.End()
+	}
+	return ie.Token.End()
+}
+
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+func (b *Boolean) End() token.Position { return b.Token.End() }
+
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	if ie.Condition != nil {
+		return ie.Condition.End()
+	}
+	return ie.Token.End()
+}
+
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position {
+	if bs.Rbrace.IsValid() {
+		return token.Position{Offset: bs.Rbrace.Offset + 1, Line: bs.Rbrace.Line, Column: bs.Rbrace.Column + 1}
+	}
+	if len(bs.Statements) > 0 {
+		return bs.Statements[len(bs.Statements)-1].End()
+	}
+	return bs.Token.End()
+}
+
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	if fl.ReturnType != nil {
+		return fl.ReturnType.End()
+	}
+	if len(fl.Parameters) > 0 {
+		return fl.Parameters[len(fl.Parameters)-1].End()
+	}
+	return fl.Token.End()
+}
+
+func (ml *MacroLiteral) Pos() token.Position { return ml.Token.Pos }
+func (ml *MacroLiteral) End() token.Position {
+	if ml.Body != nil {
+		return ml.Body.End()
+	}
+	if len(ml.Parameters) > 0 {
+		return ml.Parameters[len(ml.Parameters)-1].End()
+	}
+	return ml.Token.End()
+}
+
+func (ce *CallExpression) Pos() token.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos
+}
+
+func (ce *CallExpression) End() token.Position {
+	if ce.Rparen.IsValid() {
+		return token.Position{Offset: ce.Rparen.Offset + 1, Line: ce.Rparen.Line, Column: ce.Rparen.Column + 1}
+	}
+	if len(ce.Arguments) > 0 {
+		return ce.Arguments[len(ce.Arguments)-1].End()
+	}
+	return ce.Token.End()
+}
+
+func (nt *NamedType) Pos() token.Position { return nt.Token.Pos }
+func (nt *NamedType) End() token.Position {
+	if nt.Rbracket.IsValid() {
+		return token.Position{Offset: nt.Rbracket.Offset + 1, Line: nt.Rbracket.Line, Column: nt.Rbracket.Column + 1}
+	}
+	if len(nt.Args) > 0 {
+		return nt.Args[len(nt.Args)-1].End()
+	}
+	return nt.Token.End()
+}
+
+func (lt *ListType) Pos() token.Position { return lt.Token.Pos }
+func (lt *ListType) End() token.Position {
+	if lt.Rbracket.IsValid() {
+		return token.Position{Offset: lt.Rbracket.Offset + 1, Line: lt.Rbracket.Line, Column: lt.Rbracket.Column + 1}
+	}
+	if lt.Elem != nil {
+		return lt.Elem.End()
+	}
+	return lt.Token.End()
+}
+
+func (ft *FunctionType) Pos() token.Position { return ft.Token.Pos }
+func (ft *FunctionType) End() token.Position {
+	if ft.Result != nil {
+		return ft.Result.End()
+	}
+	if ft.Rparen.IsValid() {
+		return token.Position{Offset: ft.Rparen.Offset + 1, Line: ft.Rparen.Line, Column: ft.Rparen.Column + 1}
+	}
+	if len(ft.Params) > 0 {
+		return ft.Params[len(ft.Params)-1].End()
+	}
+	return ft.Token.End()
+}
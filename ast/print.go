@@ -0,0 +1,107 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Fprint prints the tree rooted at node to out, one node per line
+// Every node is indented two spaces deeper than its parent
+func Fprint(out io.Writer, node Node) {
+	fprint(out, node, 0)
+}
+
+// fprint is a helper function that prints a node and its children, one per line, indented by depth
+func fprint(out io.Writer, node Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch node := node.(type) {
+	case *Program:
+		fmt.Fprintf(out, "%sProgram\n", indent)
+		for _, stmt := range node.Statements {
+			fprint(out, stmt, depth+1)
+		}
+	case *LetStatement:
+		fmt.Fprintf(out, "%sLetStatement %s\n", indent, node.Name.Value)
+		if node.Type != nil {
+			fprint(out, node.Type, depth+1)
+		}
+		printChild(out, node.Value, depth+1)
+	case *ReturnStatement:
+		fmt.Fprintf(out, "%sReturnStatement\n", indent)
+		printChild(out, node.ReturnValue, depth+1)
+	case *ExpressionStatement:
+		fmt.Fprintf(out, "%sExpressionStatement\n", indent)
+		printChild(out, node.Expression, depth+1)
+	case *BlockStatement:
+		fmt.Fprintf(out, "%sBlockStatement\n", indent)
+		for _, stmt := range node.Statements {
+			fprint(out, stmt, depth+1)
+		}
+	case *Identifier:
+		fmt.Fprintf(out, "%sIdentifier %s\n", indent, node.Value)
+		if node.Type != nil {
+			fprint(out, node.Type, depth+1)
+		}
+	case *IntegerLiteral:
+		fmt.Fprintf(out, "%sIntegerLiteral %d\n", indent, node.Value)
+	case *Boolean:
+		fmt.Fprintf(out, "%sBoolean %t\n", indent, node.Value)
+	case *PrefixExpression:
+		fmt.Fprintf(out, "%sPrefixExpression %s\n", indent, node.Operator)
+		printChild(out, node.Right, depth+1)
+	case *InfixExpression:
+		fmt.Fprintf(out, "%sInfixExpression %s\n", indent, node.Operator)
+		printChild(out, node.Left, depth+1)
+		printChild(out, node.Right, depth+1)
+	case *IfExpression:
+		fmt.Fprintf(out, "%sIfExpression\n", indent)
+		printChild(out, node.Condition, depth+1)
+		if node.Consequence != nil {
+			fprint(out, node.Consequence, depth+1)
+		}
+		if node.Alternative != nil {
+			fprint(out, node.Alternative, depth+1)
+		}
+	case *FunctionLiteral:
+		params := []string{}
+		for _, pr := range node.Parameters {
+			params = append(params, pr.String())
+		}
+		fmt.Fprintf(out, "%sFunctionLiteral (%s)\n", indent, strings.Join(params, ", "))
+		if node.ReturnType != nil {
+			fprint(out, node.ReturnType, depth+1)
+		}
+		if node.Body != nil {
+			fprint(out, node.Body, depth+1)
+		}
+	case *MacroLiteral:
+		params := []string{}
+		for _, pr := range node.Parameters {
+			params = append(params, pr.String())
+		}
+		fmt.Fprintf(out, "%sMacroLiteral (%s)\n", indent, strings.Join(params, ", "))
+		if node.Body != nil {
+			fprint(out, node.Body, depth+1)
+		}
+	case *CallExpression:
+		fmt.Fprintf(out, "%sCallExpression\n", indent)
+		printChild(out, node.Function, depth+1)
+		for _, arg := range node.Arguments {
+			printChild(out, arg, depth+1)
+		}
+	case TypeExpr:
+		fmt.Fprintf(out, "%s%s %s\n", indent, kindOf(node), node)
+	default:
+		fmt.Fprintf(out, "%s%T\n", indent, node)
+	}
+}
+
+// printChild is a helper function that prints an expression that may be missing
+func printChild(out io.Writer, expr Expression, depth int) {
+	if expr == nil {
+		fmt.Fprintf(out, "%s<nil>\n", strings.Repeat("  ", depth))
+		return
+	}
+	fprint(out, expr, depth)
+}
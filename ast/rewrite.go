@@ -0,0 +1,208 @@
+package ast
+
+import "fmt"
+
+// Rewrite calls f on every node of the tree rooted at node, bottom-up, and returns the new root
+// The children of a node are rewritten before the node itself, in the order documented on Walk,
+// and f's result replaces the node in its parent. The tree is modified in place
+//
+// Returning nil from f removes the node from a list such as BlockStatement.Statements,
+// FunctionLiteral.Parameters, MacroLiteral.Parameters or CallExpression.Arguments, and clears IfExpression.Alternative and
+// the optional type annotations LetStatement.Type, Identifier.Type and FunctionLiteral.ReturnType. Anywhere else, and for a
+// replacement of the wrong type such as a Statement for InfixExpression.Left, Rewrite reports an error
+// The node is then left unchanged, the rest of the tree is still rewritten and the first error is returned
+func Rewrite(node Node, f func(Node) Node) (Node, error) {
+	r := &rewriter{f: f}
+	return r.rewrite(node), r.err
+}
+
+// rewriter holds the callback and the first error of a call to Rewrite
+type rewriter struct {
+	f   func(Node) Node
+	err error
+}
+
+func (r *rewriter) rewrite(node Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		n.Statements = r.statements(n, "Statements", n.Statements)
+	case *LetStatement:
+		if n.Name != nil {
+			n.Name = r.identifier(n, "Name", n.Name)
+		}
+		n.Type = r.typeExpr(n, "Type", n.Type, true)
+		n.Value = r.expression(n, "Value", n.Value)
+	case *ReturnStatement:
+		n.ReturnValue = r.expression(n, "ReturnValue", n.ReturnValue)
+	case *ExpressionStatement:
+		n.Expression = r.expression(n, "Expression", n.Expression)
+	case *BlockStatement:
+		n.Statements = r.statements(n, "Statements", n.Statements)
+	case *PrefixExpression:
+		n.Right = r.expression(n, "Right", n.Right)
+	case *InfixExpression:
+		n.Left = r.expression(n, "Left", n.Left)
+		n.Right = r.expression(n, "Right", n.Right)
+	case *IfExpression:
+		n.Condition = r.expression(n, "Condition", n.Condition)
+		if n.Consequence != nil {
+			n.Consequence = r.block(n, "Consequence", n.Consequence, false)
+		}
+		if n.Alternative != nil {
+			n.Alternative = r.block(n, "Alternative", n.Alternative, true)
+		}
+	case *FunctionLiteral:
+		n.Parameters = r.parameters(n, n.Parameters)
+		n.ReturnType = r.typeExpr(n, "ReturnType", n.ReturnType, true)
+		if n.Body != nil {
+			n.Body = r.block(n, "Body", n.Body, false)
+		}
+	case *MacroLiteral:
+		n.Parameters = r.parameters(n, n.Parameters)
+		if n.Body != nil {
+			n.Body = r.block(n, "Body", n.Body, false)
+		}
+	case *CallExpression:
+		n.Function = r.expression(n, "Function", n.Function)
+		args := n.Arguments[:0]
+		for _, arg := range n.Arguments {
+			if replaced := r.rewrite(arg); replaced == nil {
+				continue
+			} else if e, ok := replaced.(Expression); ok {
+				args = append(args, e)
+			} else {
+				r.fail(n, "Arguments", "ast.Expression", replaced)
+				args = append(args, arg)
+			}
+		}
+		n.Arguments = args
+	case *Identifier:
+		n.Type = r.typeExpr(n, "Type", n.Type, true)
+	case *NamedType:
+		n.Args = r.types(n, "Args", n.Args)
+	case *ListType:
+		n.Elem = r.typeExpr(n, "Elem", n.Elem, false)
+	case *FunctionType:
+		n.Params = r.types(n, "Params", n.Params)
+		n.Result = r.typeExpr(n, "Result", n.Result, false)
+	case *IntegerLiteral, *Boolean:
+		// No children
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+	return r.f(node)
+}
+
+// statements rewrites a list of statements, dropping the ones replaced by nil
+func (r *rewriter) statements(parent Node, field string, stmts []Statement) []Statement {
+	result := stmts[:0]
+	for _, stmt := range stmts {
+		replaced := r.rewrite(stmt)
+		if replaced == nil {
+			continue
+		}
+		if s, ok := replaced.(Statement); ok {
+			result = append(result, s)
+		} else {
+			r.fail(parent, field, "ast.Statement", replaced)
+			result = append(result, stmt)
+		}
+	}
+	return result
+}
+
+// expression rewrites an expression that may be missing
+func (r *rewriter) expression(parent Node, field string, expr Expression) Expression {
+	if expr == nil {
+		return nil
+	}
+	replaced := r.rewrite(expr)
+	if e, ok := replaced.(Expression); ok {
+		return e
+	}
+	r.fail(parent, field, "ast.Expression", replaced)
+	return expr
+}
+
+// typeExpr rewrites a type expression that may be missing. Only an optional one can be replaced by nil
+func (r *rewriter) typeExpr(parent Node, field string, t TypeExpr, optional bool) TypeExpr {
+	if t == nil {
+		return nil
+	}
+	replaced := r.rewrite(t)
+	if replaced == nil && optional {
+		return nil
+	}
+	if te, ok := replaced.(TypeExpr); ok {
+		return te
+	}
+	r.fail(parent, field, "ast.TypeExpr", replaced)
+	return t
+}
+
+// types rewrites a list of type expressions, dropping the ones replaced by nil
+func (r *rewriter) types(parent Node, field string, types []TypeExpr) []TypeExpr {
+	result := types[:0]
+	for _, t := range types {
+		replaced := r.rewrite(t)
+		if replaced == nil {
+			continue
+		}
+		if te, ok := replaced.(TypeExpr); ok {
+			result = append(result, te)
+		} else {
+			r.fail(parent, field, "ast.TypeExpr", replaced)
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+func (r *rewriter) parameters(parent Node, params []*Identifier) []*Identifier {
+	out := params[:0]
+	for _, pr := range params {
+		if replaced := r.rewrite(pr); replaced == nil {
+			continue
+		} else if ident, ok := replaced.(*Identifier); ok {
+			out = append(out, ident)
+		} else {
+			r.fail(parent, "Parameters", "*ast.Identifier", replaced)
+			out = append(out, pr)
+		}
+	}
+	return out
+}
+
+func (r *rewriter) identifier(parent Node, field string, ident *Identifier) *Identifier {
+	replaced := r.rewrite(ident)
+	if i, ok := replaced.(*Identifier); ok {
+		return i
+	}
+	r.fail(parent, field, "*ast.Identifier", replaced)
+	return ident
+}
+
+// block rewrites a block statement. Only an optional block can be replaced by nil
+func (r *rewriter) block(parent Node, field string, block *BlockStatement, optional bool) *BlockStatement {
+	replaced := r.rewrite(block)
+	if replaced == nil && optional {
+		return nil
+	}
+	if b, ok := replaced.(*BlockStatement); ok {
+		return b
+	}
+	r.fail(parent, field, "*ast.BlockStatement", replaced)
+	return block
+}
+
+// fail records that got cannot be stored in field of parent
+func (r *rewriter) fail(parent Node, field, want string, got Node) {
+	if r.err != nil {
+		return
+	}
+	if got == nil {
+		r.err = fmt.Errorf("ast.Rewrite: %T.%s cannot be removed", parent, field)
+	} else {
+		r.err = fmt.Errorf("ast.Rewrite: cannot use %T as %T.%s (want %s)", got, parent, field, want)
+	}
+}
@@ -0,0 +1,124 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/printer"
+	"strconv"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	program := parse(t, `let a: int = 1 + 2; log(3); f(a, 4); fn(x: bool, y) { x * 5 };`)
+	var order []string
+	root, err := ast.Rewrite(program, func(n ast.Node) ast.Node {
+		switch n := n.(type) {
+		case *ast.IntegerLiteral:
+			// Integers are doubled
+			order = append(order, n.String())
+			value := 2 * n.Value
+			tok := n.Token
+			tok.Literal = strconv.FormatInt(value, 10)
+			return &ast.IntegerLiteral{Token: tok, Value: value}
+		case *ast.InfixExpression:
+			// The children are rewritten first, so the operands are already doubled
+			order = append(order, n.String())
+		case *ast.ExpressionStatement:
+			// Calls of log are removed
+			if call, ok := n.Expression.(*ast.CallExpression); ok && call.Function.String() == "log" {
+				return nil
+			}
+		case *ast.LetStatement:
+			// Annotations are dropped
+			n.Type = nil
+		case *ast.Identifier:
+			n.Type = nil
+		}
+		return n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != program {
+		t.Errorf("the root was replaced by %v", root)
+	}
+	if got, want := printer.String(program), "let a = 2 + 4;\nf(a, 8);\nfn(x, y) {\n\tx * 10;\n};\n"; got != want {
+		t.Errorf("got\n%swant\n%s", got, want)
+	}
+	if got, want := len(order), 7; got != want {
+		t.Errorf("the callback saw %d integers and infix expressions, want %d", got, want)
+	}
+	if order[2] != "(2 + 4)" {
+		t.Errorf("the infix expression was rewritten before its operands: %q", order)
+	}
+
+	// The root itself can be replaced
+	expr, _ := ast.Rewrite(parse(t, "1;").Statements[0], func(n ast.Node) ast.Node {
+		if _, ok := n.(*ast.ExpressionStatement); ok {
+			return parse(t, "return 2;").Statements[0]
+		}
+		return n
+	})
+	if got := expr.String(); got != "return 2;" {
+		t.Errorf("the replaced root is %q, want %q", got, "return 2;")
+	}
+}
+
+func TestRewriteErrors(t *testing.T) {
+	// after is the tree once rewritten, printed by String; it is empty if nothing changes
+	tests := []struct {
+		src   string
+		f     func(ast.Node) ast.Node
+		want  string
+		after string
+	}{
+		{
+			"1 + 2;",
+			func(n ast.Node) ast.Node {
+				if _, ok := n.(*ast.IntegerLiteral); ok {
+					return nil
+				}
+				return n
+			},
+			"ast.Rewrite: *ast.InfixExpression.Left cannot be removed",
+			"",
+		},
+		{
+			"-x;",
+			func(n ast.Node) ast.Node {
+				if _, ok := n.(*ast.Identifier); ok {
+					return &ast.BlockStatement{}
+				}
+				return n
+			},
+			"ast.Rewrite: cannot use *ast.BlockStatement as *ast.PrefixExpression.Right (want ast.Expression)",
+			"",
+		},
+		{
+			"if (x) { 1 } else { 2 };",
+			func(n ast.Node) ast.Node {
+				if _, ok := n.(*ast.BlockStatement); ok {
+					return nil
+				}
+				return n
+			},
+			// The consequence stays, the rest of the tree is still rewritten
+			"ast.Rewrite: *ast.IfExpression.Consequence cannot be removed",
+			"if (x) { 1; };",
+		},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.src)
+		before := program.String()
+		_, err := ast.Rewrite(program, tt.f)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%q: got the error %v, want %q", tt.src, err, tt.want)
+		}
+		want := tt.after
+		if want == "" {
+			want = before
+		}
+		if after := program.String(); after != want {
+			t.Errorf("%q: the tree is %q after the rewrite, want %q", tt.src, after, want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sexp returns a compact S-expression form of the tree rooted at node, eg. (+ 1 (* 2 3)) for 1 + 2 * 3
+// Operators and keywords head their lists:
+//
+//	(let x 5) (return x) (block s1 s2) (if cond (block ...) (block ...)) (fn (a b) (block ...)) (call f a b)
+//
+// An annotated name is written (: x int) and an annotated result type (-> int) before the body of a
+// function. Types are written as names, (list int), (map string int) and (fn (int bool) int)
+//
+// An expression statement is written as its expression, and a Program as its statements, one per line
+func Sexp(node Node) string {
+	var out strings.Builder
+	sexp(&out, node)
+	return out.String()
+}
+
+func sexp(out *strings.Builder, node Node) {
+	if isNil(node) {
+		out.WriteString("<nil>")
+		return
+	}
+	switch n := node.(type) {
+	case *Program:
+		for i, stmt := range n.Statements {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			sexp(out, stmt)
+		}
+	case *LetStatement:
+		if n.Type != nil && n.Name != nil {
+			out.WriteString("(let ")
+			annotated(out, n.Name.Value, n.Type)
+			out.WriteString(" ")
+			sexp(out, n.Value)
+			out.WriteString(")")
+		} else {
+			list(out, "let", n.Name, n.Value)
+		}
+	case *ReturnStatement:
+		list(out, "return", n.ReturnValue)
+	case *ExpressionStatement:
+		sexp(out, n.Expression)
+	case *BlockStatement:
+		nodes := make([]Node, len(n.Statements))
+		for i, stmt := range n.Statements {
+			nodes[i] = stmt
+		}
+		list(out, "block", nodes...)
+	case *Identifier:
+		if n.Type != nil {
+			annotated(out, n.Value, n.Type)
+		} else {
+			out.WriteString(n.Value)
+		}
+	case *IntegerLiteral:
+		out.WriteString(strconv.FormatInt(n.Value, 10))
+	case *Boolean:
+		out.WriteString(strconv.FormatBool(n.Value))
+	case *PrefixExpression:
+		list(out, n.Operator, n.Right)
+	case *InfixExpression:
+		list(out, n.Operator, n.Left, n.Right)
+	case *IfExpression:
+		if n.Alternative != nil {
+			list(out, "if", n.Condition, n.Consequence, n.Alternative)
+		} else {
+			list(out, "if", n.Condition, n.Consequence)
+		}
+	case *FunctionLiteral:
+		out.WriteString("(fn (")
+		for i, pr := range n.Parameters {
+			if i > 0 {
+				out.WriteString(" ")
+			}
+			sexp(out, pr)
+		}
+		out.WriteString(") ")
+		if n.ReturnType != nil {
+			list(out, "->", n.ReturnType)
+			out.WriteString(" ")
+		}
+		sexp(out, n.Body)
+		out.WriteString(")")
+	case *MacroLiteral:
+		out.WriteString("(macro (")
+		for i, pr := range n.Parameters {
+			if i > 0 {
+				out.WriteString(" ")
+			}
+			sexp(out, pr)
+		}
+		out.WriteString(") ")
+		sexp(out, n.Body)
+		out.WriteString(")")
+	case *CallExpression:
+		nodes := []Node{n.Function}
+		for _, arg := range n.Arguments {
+			nodes = append(nodes, arg)
+		}
+		list(out, "call", nodes...)
+	case *NamedType:
+		if len(n.Args) == 0 {
+			out.WriteString(n.Name)
+		} else {
+			list(out, n.Name, typeNodes(n.Args)...)
+		}
+	case *ListType:
+		list(out, "list", n.Elem)
+	case *FunctionType:
+		out.WriteString("(fn (")
+		for i, t := range n.Params {
+			if i > 0 {
+				out.WriteString(" ")
+			}
+			sexp(out, t)
+		}
+		out.WriteString(") ")
+		sexp(out, n.Result)
+		out.WriteString(")")
+	default:
+		panic(fmt.Sprintf("ast.Sexp: unexpected node type %T", node))
+	}
+}
+
+// annotated is a helper function that writes a name with its type annotation
+func annotated(out *strings.Builder, name string, t TypeExpr) {
+	out.WriteString("(: " + name + " ")
+	sexp(out, t)
+	out.WriteString(")")
+}
+
+// typeNodes is a helper function that converts a list of type expressions to nodes
+func typeNodes(types []TypeExpr) []Node {
+	nodes := make([]Node, len(types))
+	for i, t := range types {
+		nodes[i] = t
+	}
+	return nodes
+}
+
+// list is a helper function that writes a list headed by head
+func list(out *strings.Builder, head string, nodes ...Node) {
+	out.WriteString("(" + head)
+	for _, node := range nodes {
+		out.WriteString(" ")
+		sexp(out, node)
+	}
+	out.WriteString(")")
+}
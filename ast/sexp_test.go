@@ -0,0 +1,40 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"testing"
+)
+
+func TestSexp(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		// Precedence and associativity show in the nesting
+		{"1 + 2 * 3;", "(+ 1 (* 2 3))"},
+		{"(1 + 2) * 3;", "(* (+ 1 2) 3)"},
+		{"a - b - c;", "(- (- a b) c)"},
+		{"a - (b - c);", "(- a (- b c))"},
+		{"-a * b;", "(* (- a) b)"},
+		{"!(a == b) != c < d;", "(!= (! (== a b)) (< c d))"},
+		{"f(x)(y) + 1;", "(+ (call (call f x) y) 1)"},
+		// Statements, one per line
+		{"let x = 5; return x; true;", "(let x 5)\n(return x)\ntrue"},
+		{"if (a) { b; c } else { };", "(if a (block b c) (block))"},
+		{"if (a) { b };", "(if a (block b))"},
+		{"fn(a, b) { a };", "(fn (a b) (block a))"},
+		{"macro(x) { quote(x) };", "(macro (x) (block (call quote x)))"},
+		// Annotations
+		{"let x: int = 1;", "(let (: x int) 1)"},
+		{"fn(f: fn(int, bool) -> [int], m: map[a, b]) -> bool { f };", "(fn ((: f (fn (int bool) (list int))) (: m (map a b))) (-> bool) (block f))"},
+	}
+	for _, tt := range tests {
+		if got := ast.Sexp(parse(t, tt.src)); got != tt.want {
+			t.Errorf("%q:\ngot  %s\nwant %s", tt.src, got, tt.want)
+		}
+	}
+
+	// A missing child is written <nil>
+	if got := ast.Sexp(&ast.ReturnStatement{}); got != "(return <nil>)" {
+		t.Errorf("a return without a value is %s", got)
+	}
+}
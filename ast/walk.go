@@ -0,0 +1,161 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is called for every node encountered by Walk
+// If the returned visitor w is not nil, Walk visits each of the children of node with w
+// and then calls w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node depth-first, in source order
+// It starts by calling v.Visit(node). The children of every node are visited in this order:
+//
+//	Program             Statements
+//	LetStatement        Name, Type, Value
+//	ReturnStatement     ReturnValue
+//	ExpressionStatement Expression
+//	BlockStatement      Statements
+//	PrefixExpression    Right
+//	InfixExpression     Left, Right
+//	IfExpression        Condition, Consequence, Alternative
+//	FunctionLiteral     Parameters, ReturnType, Body
+//	MacroLiteral        Parameters, Body
+//	CallExpression      Function, Arguments
+//	Identifier          Type
+//	NamedType           Args
+//	ListType            Elem
+//	FunctionType        Params, Result
+//
+// IntegerLiteral and Boolean have no children. Missing (nil) children are skipped
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			Walk(v, n.ReturnValue)
+		}
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+	case *PrefixExpression:
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+	case *InfixExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+	case *IfExpression:
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+	case *FunctionLiteral:
+		for _, pr := range n.Parameters {
+			Walk(v, pr)
+		}
+		if n.ReturnType != nil {
+			Walk(v, n.ReturnType)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *MacroLiteral:
+		for _, pr := range n.Parameters {
+			Walk(v, pr)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *CallExpression:
+		if n.Function != nil {
+			Walk(v, n.Function)
+		}
+		for _, arg := range n.Arguments {
+			if arg != nil {
+				Walk(v, arg)
+			}
+		}
+	case *Identifier:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+	case *NamedType:
+		walkTypes(v, n.Args)
+	case *ListType:
+		if n.Elem != nil {
+			Walk(v, n.Elem)
+		}
+	case *FunctionType:
+		walkTypes(v, n.Params)
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+	case *IntegerLiteral, *Boolean:
+		// No children
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+	v.Visit(nil)
+}
+
+// walkStatements is a helper function that walks a list of statements
+func walkStatements(v Visitor, stmts []Statement) {
+	for _, stmt := range stmts {
+		if stmt != nil {
+			Walk(v, stmt)
+		}
+	}
+}
+
+// walkTypes is a helper function that walks a list of type expressions
+func walkTypes(v Visitor, types []TypeExpr) {
+	for _, t := range types {
+		if t != nil {
+			Walk(v, t)
+		}
+	}
+}
+
+// inspector adapts a function to the Visitor interface
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in the same order as Walk
+// It calls f(node) for every node. If f returns true, Inspect visits the children of node and then calls f(nil)
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
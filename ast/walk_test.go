@@ -0,0 +1,98 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkey/ast"
+	"strings"
+	"testing"
+)
+
+// recorder is a Visitor that writes every node it visits, indented by depth, and an end line
+// for every call of Visit(nil)
+type recorder struct {
+	b     *strings.Builder
+	depth int
+}
+
+func (r recorder) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		fmt.Fprintf(r.b, "%send\n", strings.Repeat("  ", r.depth-1))
+		return nil
+	}
+	fmt.Fprintf(r.b, "%s%T %s\n", strings.Repeat("  ", r.depth), node, node.TokenLiteral())
+	return recorder{r.b, r.depth + 1}
+}
+
+func TestWalkOrder(t *testing.T) {
+	program := parse(t, `let f = fn(a: [int], b) -> bool { if (a) { -b } else { g(a, 1) } };`)
+	var b strings.Builder
+	ast.Walk(recorder{b: &b}, program)
+	want := `*ast.Program let
+  *ast.LetStatement let
+    *ast.Identifier f
+    end
+    *ast.FunctionLiteral fn
+      *ast.Identifier a
+        *ast.ListType [
+          *ast.NamedType int
+          end
+        end
+      end
+      *ast.Identifier b
+      end
+      *ast.NamedType bool
+      end
+      *ast.BlockStatement {
+        *ast.ExpressionStatement if
+          *ast.IfExpression if
+            *ast.Identifier a
+            end
+            *ast.BlockStatement {
+              *ast.ExpressionStatement -
+                *ast.PrefixExpression -
+                  *ast.Identifier b
+                  end
+                end
+              end
+            end
+            *ast.BlockStatement {
+              *ast.ExpressionStatement g
+                *ast.CallExpression (
+                  *ast.Identifier g
+                  end
+                  *ast.Identifier a
+                  end
+                  *ast.IntegerLiteral 1
+                  end
+                end
+              end
+            end
+          end
+        end
+      end
+    end
+  end
+end
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%swant\n%s", got, want)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	program := parse(t, `let x = 1 + 2 * 3; fn(y) { y - x };`)
+	var visited []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		visited = append(visited, n.String())
+		// The body of the function is skipped
+		_, isFunction := n.(*ast.FunctionLiteral)
+		return !isFunction
+	})
+	want := []string{"let x = (1 + (2 * 3));fn(y) { (y - x); };", "let x = (1 + (2 * 3));", "x", "(1 + (2 * 3))", "1", "(2 * 3)", "2", "3", "fn(y) { (y - x); };", "fn(y) { (y - x); }"}
+	if strings.Join(visited, "|") != strings.Join(want, "|") {
+		t.Errorf("visited\n%q\nwant\n%q", visited, want)
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"monkey/ast"
+	"monkey/cst"
+	"monkey/format"
+	"monkey/lexer"
+	"monkey/lsp"
+	"monkey/macro"
+	"monkey/optimize"
+	"monkey/parser"
+	"monkey/resolver"
+	"monkey/token"
+	"monkey/types"
+	"monkey/vet"
+	"os"
+	"sort"
+	"strings"
+)
+
+// parseFlags parses the flags of a subcommand and returns its file arguments
+// It returns false if the command line is wrong
+func parseFlags(fs *flag.FlagSet, args []string, minFiles, maxFiles int) ([]string, bool) {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return nil, false
+	}
+	files := fs.Args()
+	if len(files) < minFiles || maxFiles >= 0 && len(files) > maxFiles {
+		fmt.Fprintf(os.Stderr, "monkey %s: expected a file name or -\n", fs.Name())
+		return nil, false
+	}
+	return files, true
+}
+
+// parseFile reads and parses the file at path
+// Read and parser errors are printed to the standard error
+// The parser mode is combined with ParseComments and AllErrors
+func parseFile(path string, mode parser.Mode) (*ast.Program, string, bool) {
+	src, err := readSource(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return nil, "", false
+	}
+	program, err := parser.ParseFile(displayName(path), src, mode|parser.ParseComments|parser.AllErrors)
+	if err != nil {
+		printErrors(path, err)
+		return nil, "", false
+	}
+	return program, src, true
+}
+
+// printErrors prints the parser errors of the file at path to the standard error, one per line
+func printErrors(path string, err error) {
+	list, ok := err.(parser.ErrorList)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", displayName(path), err)
+		return
+	}
+	for _, e := range list {
+		e.Filename = displayName(path)
+		fmt.Fprintln(os.Stderr, e)
+	}
+}
+
+func runTokens(args []string) int {
+	fs := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the tokens as a JSON array, ending with the EOF token")
+	files, ok := parseFlags(fs, args, 1, 1)
+	if !ok {
+		return exitUsage
+	}
+	// The file is lexed as it is read, so large files are never loaded whole
+	f, err := openSource(files[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return exitFailure
+	}
+	defer f.Close()
+	l := lexer.NewReader(f)
+	tokens := []token.Token{}
+	for tok := l.NextToken(); ; tok = l.NextToken() {
+		if *asJSON {
+			tokens = append(tokens, tok)
+		} else if tok.Type != token.EOF {
+			fmt.Printf("%-8s %-10s %q\n", tok.Pos, tok.Type, tok.Literal)
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	if err := l.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s: %s\n", displayName(files[0]), err)
+		return exitFailure
+	}
+	if *asJSON {
+		return printJSON(tokens)
+	}
+	return exitOK
+}
+
+func runParse(args []string) int {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	form := fs.String("format", "tree", "the form of the output: tree, json, dot (Graphviz), sexp (S-expressions) or cst (concrete syntax tree)")
+	asJSON := fs.Bool("json", false, "short for -format=json")
+	traced := fs.Bool("trace", false, "write a trace of the parsing functions to the standard error")
+	files, ok := parseFlags(fs, args, 1, 1)
+	if !ok {
+		return exitUsage
+	}
+	if *asJSON {
+		*form = "json"
+	}
+	var mode parser.Mode
+	if *traced {
+		mode = parser.Trace
+	}
+	switch *form {
+	case "tree", "json", "dot", "sexp", "cst":
+	default:
+		fmt.Fprintf(os.Stderr, "monkey parse: unknown format %q\n", *form)
+		return exitUsage
+	}
+	program, src, ok := parseFile(files[0], mode)
+	if !ok {
+		return exitFailure
+	}
+	switch *form {
+	case "tree":
+		ast.Fprint(os.Stdout, program)
+	case "json":
+		data, err := ast.ToJSON(program)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			return exitFailure
+		}
+		os.Stdout.Write(append(data, '\n'))
+	case "dot":
+		ast.Dot(os.Stdout, program)
+	case "sexp":
+		fmt.Println(ast.Sexp(program))
+	case "cst":
+		cst.Fprint(os.Stdout, cst.Build(src, program).Root)
+	}
+	return exitOK
+}
+
+// printJSON prints v as JSON on a single line
+func printJSON(v interface{}) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return exitFailure
+	}
+	os.Stdout.Write(append(data, '\n'))
+	return exitOK
+}
+
+// runFmt prints the formatted source of a file
+// With -d it prints a diff against the file instead and with -w it rewrites the file in place
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	diff := fs.Bool("d", false, "print a diff instead of the formatted source")
+	write := fs.Bool("w", false, "write the formatted source back to the file")
+	files, ok := parseFlags(fs, args, 1, -1)
+	if !ok {
+		return exitUsage
+	}
+	code := exitOK
+	for _, path := range files {
+		if *write && path == "-" {
+			fmt.Fprintln(os.Stderr, "monkey fmt: cannot use -w with the standard input")
+			return exitUsage
+		}
+		if !formatFile(path, *diff, *write) {
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// formatFile formats a single file for runFmt
+func formatFile(path string, diff, write bool) bool {
+	src, err := readSource(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return false
+	}
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		printErrors(path, err)
+		return false
+	}
+	if diff {
+		name := displayName(path)
+		os.Stdout.Write(unifiedDiff(name+".orig", name, []byte(src), out))
+	}
+	if write {
+		if string(out) == src {
+			return true
+		}
+		if err := ioutil.WriteFile(path, out, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			return false
+		}
+	}
+	if !diff && !write {
+		os.Stdout.Write(out)
+	}
+	return true
+}
+
+// runCheck parses every file and prints their errors
+// The exit code is non-zero if any file has errors
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	withTypes := fs.Bool("types", false, "also infer the types and report the type errors")
+	files, ok := parseFlags(fs, args, 1, -1)
+	if !ok {
+		return exitUsage
+	}
+	code := exitOK
+	for _, path := range files {
+		program, _, ok := parseFile(path, 0)
+		if !ok {
+			code = exitFailure
+			continue
+		}
+		if !checkNames(path, program) {
+			code = exitFailure
+		}
+		if *withTypes && !checkTypes(path, program) {
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// checkTypes prints the type errors of a parsed file to the standard error
+// It returns false if there are any
+func checkTypes(path string, program *ast.Program) bool {
+	errs := types.Check(program).Errors
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", displayName(path), err)
+	}
+	return len(errs) == 0
+}
+
+// checkNames prints the name problems of a parsed file to the standard error
+// Shadowing is printed as a warning; it returns false if there are other problems
+func checkNames(path string, program *ast.Program) bool {
+	ok := true
+	for _, p := range resolver.Resolve(program).Problems {
+		if p.IsError() {
+			ok = false
+			fmt.Fprintf(os.Stderr, "%s:%s\n", displayName(path), p)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s:%s: warning: %s\n", displayName(path), p.Pos(), p.Msg)
+		}
+	}
+	return ok
+}
+
+// runClosures prints the free variables of every function literal of a file, in source order
+func runClosures(args []string) int {
+	fs := flag.NewFlagSet("closures", flag.ContinueOnError)
+	files, ok := parseFlags(fs, args, 1, 1)
+	if !ok {
+		return exitUsage
+	}
+	program, _, ok := parseFile(files[0], 0)
+	if !ok {
+		return exitFailure
+	}
+	info := resolver.Resolve(program)
+	var closures []*resolver.Closure
+	for _, c := range info.Closures {
+		closures = append(closures, c)
+	}
+	sort.Slice(closures, func(i, j int) bool { return closures[i].Func.Pos().Offset < closures[j].Func.Pos().Offset })
+	for _, c := range closures {
+		captures := make([]string, len(c.Captures))
+		for i, capture := range c.Captures {
+			captures[i] = fmt.Sprintf("%s (%d up", capture.Symbol.Name, capture.Depth)
+			if capture.Reassigned {
+				captures[i] += ", reassigned"
+			}
+			captures[i] += ")"
+		}
+		globals := make([]string, len(c.Globals))
+		for i, sym := range c.Globals {
+			globals[i] = sym.Name
+		}
+		fmt.Printf("%s:%s: depth %d captures [%s] globals [%s]\n", displayName(files[0]), c.Func.Pos(), c.Depth, strings.Join(captures, ", "), strings.Join(globals, ", "))
+	}
+	return exitOK
+}
+
+// runExpand prints a file after expanding its macros, in the canonical format
+func runExpand(args []string) int {
+	fs := flag.NewFlagSet("expand", flag.ContinueOnError)
+	files, ok := parseFlags(fs, args, 1, 1)
+	if !ok {
+		return exitUsage
+	}
+	program, src, ok := parseFile(files[0], 0)
+	if !ok {
+		return exitFailure
+	}
+	if err := macro.Expand(program, macro.Define(program)); err != nil {
+		printErrors(files[0], err)
+		return exitFailure
+	}
+	fmt.Print(format.DefaultConfig.Edited(program, src))
+	return exitOK
+}
+
+// runOptimize prints a file after folding its constant expressions and removing its dead code,
+// in the canonical format
+func runOptimize(args []string) int {
+	fs := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	withStats := fs.Bool("stats", false, "print how much dead code was removed to the standard error")
+	files, ok := parseFlags(fs, args, 1, 1)
+	if !ok {
+		return exitUsage
+	}
+	program, src, ok := parseFile(files[0], 0)
+	if !ok {
+		return exitFailure
+	}
+	stats := optimize.Program(program)
+	if *withStats {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", displayName(files[0]), stats)
+	}
+	// The removed statements would leave their lines empty
+	fmt.Print(format.DefaultConfig.Edited(program, src))
+	return exitOK
+}
+
+// runVet reports the findings of the vet rules in every file
+// The exit code is non-zero if any file has errors or findings
+func runVet(args []string) int {
+	fs := flag.NewFlagSet("vet", flag.ContinueOnError)
+	enable := fs.String("enable", "", "run only the comma separated `rules`")
+	disable := fs.String("disable", "", "do not run the comma separated `rules`")
+	list := fs.Bool("list", false, "list the rules and exit")
+	files, ok := parseFlags(fs, args, 0, -1)
+	if !ok {
+		return exitUsage
+	}
+	if *list {
+		for _, r := range vet.Rules() {
+			fmt.Printf("%-12s %s\n", r.Name, r.Doc)
+		}
+		return exitOK
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "monkey vet: expected a file name or -")
+		return exitUsage
+	}
+	rules, err := selectRules(*enable, *disable)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey vet: %s\n", err)
+		return exitUsage
+	}
+	code := exitOK
+	for _, path := range files {
+		program, _, ok := parseFile(path, 0)
+		if !ok {
+			code = exitFailure
+			continue
+		}
+		for _, f := range vet.Check(program, rules) {
+			fmt.Printf("%s:%s\n", displayName(path), f)
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// selectRules is a helper function that returns the vet rules named in enable, or all of them if it
+// is empty, less those named in disable
+func selectRules(enable, disable string) ([]*vet.Rule, error) {
+	names := func(list string) (map[string]bool, error) {
+		set := map[string]bool{}
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if vet.Lookup(name) == nil {
+				return nil, fmt.Errorf("unknown rule %q", name)
+			}
+			set[name] = true
+		}
+		return set, nil
+	}
+	enabled, err := names(enable)
+	if err != nil {
+		return nil, err
+	}
+	disabled, err := names(disable)
+	if err != nil {
+		return nil, err
+	}
+	var rules []*vet.Rule
+	for _, r := range vet.Rules() {
+		if (len(enabled) == 0 || enabled[r.Name]) && !disabled[r.Name] {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// runLSP serves the Language Server Protocol on the standard input and output
+func runLSP(args []string) int {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	if _, ok := parseFlags(fs, args, 0, 0); !ok {
+		return exitUsage
+	}
+	if err := lsp.NewServer().Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "monkey lsp: %s\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,414 @@
+// Package cst builds lossless concrete syntax trees of Monkey source
+//
+// A concrete syntax tree keeps every byte of the source. Its leaves are the tokens, including
+// illegal ones, the runs of white space and the comments, and concatenating them in order gives
+// the source back exactly. The other nodes follow the ast.Program parsed from the same source,
+// so the CST node of any ast.Node can be looked up, and a tool can edit the bytes of a single
+// node and leave the rest of the source untouched
+package cst
+
+import (
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strings"
+)
+
+// Kind is the kind of a CST node
+// The kind of a node built for an ast.Node is the name of its type, eg. "LetStatement"
+type Kind string
+
+// The kinds of the nodes that have no ast.Node
+const (
+	TokenKind      Kind = "Token"           // A token, a leaf
+	WhitespaceKind Kind = "Whitespace"      // A run of white space, a leaf
+	CommentKind    Kind = "Comment"         // A comment, a leaf
+	ParenKind      Kind = "ParenExpression" // Grouping parentheses around an expression, which the ast drops
+)
+
+// Node is a node of a concrete syntax tree
+// Start and End are the byte offsets of the source the node covers
+type Node struct {
+	Kind     Kind
+	Start    int
+	End      int
+	Text     string      // The source of a leaf
+	Token    token.Token // The token of a TokenKind leaf
+	AST      ast.Node    // The ast node this node was built for, nil for leaves and parentheses
+	Children []*Node     // The children in source order, nil for leaves
+}
+
+// IsLeaf reports whether n is a token, white space or a comment
+func (n *Node) IsLeaf() bool {
+	return n.Children == nil && n.AST == nil && n.Kind != ParenKind
+}
+
+// String returns the source covered by n, built from its leaves
+func (n *Node) String() string {
+	var out strings.Builder
+	n.writeTo(&out)
+	return out.String()
+}
+
+func (n *Node) writeTo(out *strings.Builder) {
+	if n.IsLeaf() {
+		out.WriteString(n.Text)
+		return
+	}
+	for _, c := range n.Children {
+		c.writeTo(out)
+	}
+}
+
+// Tree is the concrete syntax tree of a source
+type Tree struct {
+	Root    *Node        // The node of the ast.Program, which covers the whole source
+	Program *ast.Program // The ast the tree was built with
+	nodes   map[ast.Node]*Node
+}
+
+// Parse parses src and builds its concrete syntax tree
+// The tree is built even if there are parse errors, which are returned as a parser.ErrorList
+func Parse(src string) (*Tree, error) {
+	program, err := parser.ParseFile("", src, parser.ParseComments|parser.AllErrors)
+	return Build(src, program), err
+}
+
+// Build builds the concrete syntax tree of src from program, which must have been parsed from src
+// Leaves that do not belong to any node of a partially parsed program are kept by the closest
+// enclosing node, so the tree is lossless even for source with errors
+func Build(src string, program *ast.Program) *Tree {
+	b := &builder{
+		leaves:  scan(src),
+		tokenAt: map[int]int{},
+		tokenTo: map[int]int{},
+		group:   map[int]int{},
+		spans:   map[ast.Node]span{},
+		tree:    &Tree{Program: program, nodes: map[ast.Node]*Node{}},
+	}
+	for i, leaf := range b.leaves {
+		if leaf.Kind == TokenKind {
+			b.tokenAt[leaf.Start] = i
+			b.tokenTo[leaf.End] = i
+		}
+	}
+	b.findGroups(program)
+	root := b.build(program, span{0, len(b.leaves) - 1})
+	root.Start, root.End = 0, len(src)
+	b.tree.Root = root
+	return b.tree
+}
+
+// Node returns the CST node of n, or nil if n is not part of the tree
+func (t *Tree) Node(n ast.Node) *Node {
+	return t.nodes[n]
+}
+
+// String returns the source of the tree, byte for byte
+func (t *Tree) String() string {
+	return t.Root.String()
+}
+
+// Leaves returns the leaves of the tree rooted at n in source order
+func Leaves(n *Node) []*Node {
+	if n.IsLeaf() {
+		return []*Node{n}
+	}
+	var leaves []*Node
+	for _, c := range n.Children {
+		leaves = append(leaves, Leaves(c)...)
+	}
+	return leaves
+}
+
+// Fprint prints the tree rooted at n to out, one node per line
+// Every node is indented two spaces deeper than its parent, leaves are printed with their source
+func Fprint(out io.Writer, n *Node) {
+	fprint(out, n, 0)
+}
+
+func fprint(out io.Writer, n *Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch {
+	case n.Kind == TokenKind:
+		fmt.Fprintf(out, "%s%s %q [%d,%d)\n", indent, n.Token.Type, n.Text, n.Start, n.End)
+	case n.IsLeaf():
+		fmt.Fprintf(out, "%s%s %q [%d,%d)\n", indent, n.Kind, n.Text, n.Start, n.End)
+	default:
+		fmt.Fprintf(out, "%s%s [%d,%d)\n", indent, n.Kind, n.Start, n.End)
+		for _, c := range n.Children {
+			fprint(out, c, depth+1)
+		}
+	}
+}
+
+// scan is a helper function that splits src into leaves
+// The lexer gives the tokens and the comments, the gaps between them are white space
+func scan(src string) []*Node {
+	l := lexer.New(src)
+	var tokens []token.Token
+	for it := l.Iterator(); it.Next(); {
+		tokens = append(tokens, it.Token())
+	}
+	comments := l.Comments()
+	var leaves []*Node
+	offset := 0
+	gap := func(end int) {
+		if end <= offset {
+			return
+		}
+		text := src[offset:end]
+		if strings.TrimLeft(text, " \t\r\n") == "" {
+			leaves = append(leaves, &Node{Kind: WhitespaceKind, Start: offset, End: end, Text: text})
+		} else {
+			// Only what the lexer never reached, after a NUL byte, is not white space
+			tok := token.Token{Type: token.ILLEGAL, Literal: text}
+			leaves = append(leaves, &Node{Kind: TokenKind, Start: offset, End: end, Text: text, Token: tok})
+		}
+		offset = end
+	}
+	for len(tokens) > 0 || len(comments) > 0 {
+		var tok token.Token
+		kind := TokenKind
+		if len(comments) > 0 && (len(tokens) == 0 || comments[0].Pos.Offset < tokens[0].Pos.Offset) {
+			tok, comments, kind = comments[0], comments[1:], CommentKind
+		} else {
+			tok, tokens = tokens[0], tokens[1:]
+		}
+		gap(tok.Pos.Offset)
+		end := tok.End().Offset
+		leaves = append(leaves, &Node{Kind: kind, Start: offset, End: end, Text: src[offset:end], Token: tok})
+		offset = end
+	}
+	gap(len(src))
+	return leaves
+}
+
+// span is a range of leaves, both ends included
+type span struct {
+	first, last int
+}
+
+// builder builds the nodes of a tree from its leaves
+type builder struct {
+	leaves  []*Node
+	tokenAt map[int]int // The index of the token leaf starting at an offset
+	tokenTo map[int]int // The index of the token leaf ending at an offset
+	group   map[int]int // The index of the ')' matching a grouping '(', by the index of the '('
+	spans   map[ast.Node]span
+	tree    *Tree
+}
+
+// findGroups finds the grouping parentheses, the pairs of parentheses that are not part of the syntax
+// of a call, a function literal, a function type or an if expression
+func (b *builder) findGroups(program *ast.Program) {
+	syntax := map[int]bool{} // The leaf indexes of the parentheses of the syntax
+	mark := func(i int, ok bool) {
+		if ok {
+			syntax[i] = true
+		}
+	}
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpression:
+			mark(b.tokenAt[n.Token.Pos.Offset], n.Token.Pos.IsValid())
+			mark(b.tokenAt[n.Rparen.Offset], n.Rparen.IsValid())
+		case *ast.IfExpression:
+			b.markAround(n.Token, n.Consequence, mark)
+		case *ast.FunctionLiteral:
+			b.markAround(n.Token, n.Body, mark)
+		case *ast.MacroLiteral:
+			b.markAround(n.Token, n.Body, mark)
+		case *ast.FunctionType:
+			b.markAround(n.Token, nil, mark)
+			mark(b.tokenAt[n.Rparen.Offset], n.Rparen.IsValid())
+		}
+		return true
+	})
+	var open []int
+	for i, leaf := range b.leaves {
+		switch {
+		case leaf.Kind != TokenKind:
+		case leaf.Token.Type == token.LPAREN:
+			open = append(open, i)
+		case leaf.Token.Type == token.RPAREN && len(open) > 0:
+			o := open[len(open)-1]
+			open = open[:len(open)-1]
+			if !syntax[o] && !syntax[i] {
+				b.group[o] = i
+			}
+		}
+	}
+}
+
+// markAround is a helper function that marks the '(' after the keyword kw and the ')' before block
+func (b *builder) markAround(kw token.Token, block *ast.BlockStatement, mark func(int, bool)) {
+	if i, ok := b.tokenAt[kw.Pos.Offset]; ok {
+		if next := b.nextToken(i); next >= 0 {
+			mark(next, b.leaves[next].Token.Type == token.LPAREN)
+		}
+	}
+	if block == nil {
+		return
+	}
+	if i, ok := b.tokenAt[block.Token.Pos.Offset]; ok {
+		if prev := b.prevToken(i); prev >= 0 {
+			mark(prev, b.leaves[prev].Token.Type == token.RPAREN)
+		}
+	}
+}
+
+// nextToken is a helper function that returns the index of the first token leaf after i, or -1
+func (b *builder) nextToken(i int) int {
+	for i++; i < len(b.leaves); i++ {
+		if b.leaves[i].Kind == TokenKind {
+			return i
+		}
+	}
+	return -1
+}
+
+// prevToken is a helper function that returns the index of the last token leaf before i, or -1
+func (b *builder) prevToken(i int) int {
+	for i--; i >= 0; i-- {
+		if b.leaves[i].Kind == TokenKind {
+			return i
+		}
+	}
+	return -1
+}
+
+// span returns the leaves covered by n and its children, including the grouping parentheses
+// around its children. ok is false if the position of n does not match the leaves
+func (b *builder) span(n ast.Node) (span, bool) {
+	if s, ok := b.spans[n]; ok {
+		return s, true
+	}
+	first, ok1 := b.tokenAt[n.Pos().Offset]
+	last, ok2 := b.tokenTo[n.End().Offset]
+	if !n.Pos().IsValid() || !ok1 || !ok2 || last < first {
+		return span{}, false
+	}
+	s := span{first, last}
+	for _, c := range children(n) {
+		if cs, ok := b.wrapped(c); ok {
+			s.first = min(s.first, cs.first)
+			s.last = max(s.last, cs.last)
+		}
+	}
+	if _, ok := n.(ast.Statement); ok && !isBlock(n) {
+		// The semicolon that ends a statement belongs to it
+		if next := b.nextToken(s.last); next >= 0 && b.leaves[next].Token.Type == token.SEMICOLON {
+			s.last = next
+		}
+	}
+	b.spans[n] = s
+	return s, true
+}
+
+// wrapped returns the span of n widened to the grouping parentheses around it
+func (b *builder) wrapped(n ast.Node) (span, bool) {
+	s, ok := b.span(n)
+	if !ok {
+		return s, false
+	}
+	for {
+		o, c := b.prevToken(s.first), b.nextToken(s.last)
+		if o < 0 || c < 0 || b.group[o] != c {
+			return s, true
+		}
+		s = span{o, c}
+	}
+}
+
+// build builds the node of n over the leaves of s
+func (b *builder) build(n ast.Node, s span) *Node {
+	node := &Node{Kind: kindOf(n), AST: n, Children: []*Node{}}
+	b.tree.nodes[n] = node
+	kids := children(n)
+	for i := s.first; i <= s.last; {
+		if len(kids) > 0 {
+			ks, ok := b.wrapped(kids[0])
+			if !ok || ks.first < i || ks.last > s.last {
+				// A child whose leaves do not fit, in a partially parsed tree, leaves them to n
+				kids = kids[1:]
+				continue
+			}
+			if ks.first == i {
+				node.Children = append(node.Children, b.buildWrapped(kids[0], ks))
+				kids = kids[1:]
+				i = ks.last + 1
+				continue
+			}
+		}
+		node.Children = append(node.Children, b.leaves[i])
+		i++
+	}
+	setRange(node, s, b.leaves)
+	return node
+}
+
+// buildWrapped builds the node of n inside the grouping parentheses of s, one ParenKind node per pair
+func (b *builder) buildWrapped(n ast.Node, s span) *Node {
+	inner, _ := b.span(n)
+	if s == inner {
+		return b.build(n, s)
+	}
+	node := &Node{Kind: ParenKind, Children: []*Node{}}
+	first, last := b.nextToken(s.first), b.prevToken(s.last)
+	node.Children = append(node.Children, b.leaves[s.first:first]...)
+	node.Children = append(node.Children, b.buildWrapped(n, span{first, last}))
+	node.Children = append(node.Children, b.leaves[last+1:s.last+1]...)
+	setRange(node, s, b.leaves)
+	return node
+}
+
+// setRange is a helper function that sets the offsets of a node that covers the leaves of s
+func setRange(node *Node, s span, leaves []*Node) {
+	if s.first <= s.last {
+		node.Start, node.End = leaves[s.first].Start, leaves[s.last].End
+	}
+}
+
+// children is a helper function that returns the children of n in source order
+func children(n ast.Node) []ast.Node {
+	var kids []ast.Node
+	ast.Inspect(n, func(c ast.Node) bool {
+		if c == n {
+			return true
+		}
+		if c != nil {
+			kids = append(kids, c)
+		}
+		return false
+	})
+	return kids
+}
+
+// isBlock is a helper function that checks if n is a block statement
+func isBlock(n ast.Node) bool {
+	_, ok := n.(*ast.BlockStatement)
+	return ok
+}
+
+// kindOf is a helper function that returns the kind of the node of n
+func kindOf(n ast.Node) Kind {
+	return Kind(strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."))
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
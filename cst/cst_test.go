@@ -0,0 +1,125 @@
+package cst
+
+import (
+	"math/rand"
+	"monkey/ast"
+	"strings"
+	"testing"
+)
+
+// corpus has valid source with unusual layout and source with errors
+var corpus = []string{
+	"",
+	"   \n\t\n",
+	"// only a comment",
+	"let x = 5;",
+	"let   x\t=\n5 ;   // five\r\n\r\nx",
+	"let add = fn(a: int, b) -> int { // adds\n\treturn ((a)) + (b);\n};\nadd(1, (2));",
+	"if ((x)) { } else { // nothing\n}\n(((1)));",
+	"let m = macro(c, body) { quote(if (unquote(c)) { unquote(body) }) };\n  m(x > 1, puts(x))",
+	"let f: fn(int, [bool]) -> map[int, a] = fn() {};",
+	// Errors
+	"let = 1;",
+	"let x = ;",
+	"let f = fn(x) { x",
+	"fn(1, 2) { 1 };",
+	"if (x { 1 } else",
+	"a + + * / b;;; ) ( } {",
+	"let x = 1 @ 2 # é;\xff\xfe",
+	"let x = (1 + 2;\nlet y = 3)",
+	"return",
+	"x(1, 2,",
+	"// c1\nlet /* not a comment */ y = 2; // c2",
+	"let a: [int = 1; let b: fn(int -> int = 2;",
+	"\x00let x = 1;",
+}
+
+// checkTree fails the test if the tree of src does not give src back, or if a node does not
+// cover the source its leaves give
+func checkTree(t *testing.T, src string) *Tree {
+	t.Helper()
+	tree, _ := Parse(src)
+	if got := tree.String(); got != src {
+		t.Fatalf("%q printed back as %q", src, got)
+	}
+	var check func(n *Node)
+	check = func(n *Node) {
+		if got := n.String(); got != src[n.Start:n.End] {
+			t.Fatalf("%q: the %s node at %d-%d prints %q, but covers %q", src, n.Kind, n.Start, n.End, got, src[n.Start:n.End])
+		}
+		for _, c := range n.Children {
+			check(c)
+		}
+	}
+	check(tree.Root)
+	return tree
+}
+
+func TestLossless(t *testing.T) {
+	for _, src := range corpus {
+		checkTree(t, src)
+	}
+}
+
+func TestLosslessRandomEdits(t *testing.T) {
+	pieces := []string{"let", " ", "\n", "\r\n", "\t", "x", "==", "=", "!", "// c\n", "/", "12", "(", ")", "{", "}", ";", ",", ":", "->", "[", "]", "fn", "if", "else", "return", "macro", "é", "\xff", "@"}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		src := corpus[r.Intn(len(corpus))]
+		for k := r.Intn(6); k >= 0; k-- {
+			at := r.Intn(len(src) + 1)
+			end := at + r.Intn(3)
+			if end > len(src) {
+				end = len(src)
+			}
+			src = src[:at] + pieces[r.Intn(len(pieces))] + src[end:]
+		}
+		checkTree(t, src)
+	}
+}
+
+func TestNodeOf(t *testing.T) {
+	src := "let add = fn(a, b) { // adds\n\t(a + b) * 2\n};\nadd((1), 2);"
+	tree := checkTree(t, src)
+	ast.Inspect(tree.Program, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		c := tree.Node(n)
+		if c == nil {
+			t.Errorf("the %T %s has no CST node", n, n)
+			return true
+		}
+		if c.AST != n {
+			t.Errorf("the CST node of the %T %s was built for %v", n, n, c.AST)
+		}
+		// A node starts at its first token, only the parentheses around it may come before
+		if start := strings.TrimLeft(src[c.Start:n.Pos().Offset], "( "); start != "" {
+			t.Errorf("the CST node of the %T %s starts with %q", n, n, src[c.Start:n.Pos().Offset])
+		}
+		return true
+	})
+
+	// The grouping parentheses are kept as their own node
+	sum := tree.Program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral).Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression).Left
+	if got := tree.Node(sum).String(); got != "a + b" {
+		t.Errorf("the sum is %q", got)
+	}
+	var parens []string
+	var find func(n *Node)
+	find = func(n *Node) {
+		if n.Kind == ParenKind {
+			parens = append(parens, n.String())
+		}
+		for _, c := range n.Children {
+			find(c)
+		}
+	}
+	find(tree.Root)
+	if got := strings.Join(parens, " "); got != "(a + b) (1)" {
+		t.Errorf("the parenthesized expressions are %s", got)
+	}
+	if tree.Node(&ast.Identifier{}) != nil {
+		t.Errorf("a node of another tree has a CST node")
+	}
+}
@@ -0,0 +1,159 @@
+package format
+
+import "strings"
+
+// The formatter lays out source with a small document algebra in the style of Wadler's
+// "A prettier printer". A document is built from text, line breaks, nesting and groups.
+// Every group is printed flat, with its line breaks turned into spaces, if it fits in the
+// remaining width of the line, and broken otherwise
+
+type doc interface{}
+
+// text is printed as is. It never contains a newline
+type text string
+
+// line is a space in a flat group and a newline otherwise. A soft line is nothing in a flat group
+// A hard line is always a newline
+type line struct {
+	soft bool
+	hard bool
+}
+
+var (
+	space    = line{}
+	softline = line{soft: true}
+	hardline = line{hard: true}
+)
+
+// concat is printed element by element
+type concat []doc
+
+// nest indents the lines that start inside it by one more tab when it is broken
+// A hard nest, used for the body of a block, indents even in a flat group
+type nest struct {
+	doc  doc
+	hard bool
+}
+
+// group is printed flat if it fits, broken otherwise
+type group struct {
+	doc doc
+}
+
+// join is a helper function that puts sep between the docs
+func join(docs []doc, sep doc) doc {
+	out := concat{}
+	for i, d := range docs {
+		if i > 0 {
+			out = append(out, sep)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// item is a document waiting to be printed, with the indentation and mode it is printed in
+type item struct {
+	indent int
+	flat   bool
+	doc    doc
+}
+
+// render prints d so that lines stay within width columns where possible
+// A tab counts as tabWidth columns
+func render(d doc, width, tabWidth int) string {
+	var out strings.Builder
+	col := 0
+	lineStart := true
+	indent := 0
+	stack := []item{{0, false, d}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := it.doc.(type) {
+		case text:
+			if d == "" {
+				continue
+			}
+			if lineStart {
+				// Indentation is written lazily so that empty lines have no trailing tabs
+				out.WriteString(strings.Repeat("\t", indent))
+				col = indent * tabWidth
+				lineStart = false
+			}
+			out.WriteString(string(d))
+			col += len(d)
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, item{it.indent, it.flat, d[i]})
+			}
+		case nest:
+			stack = append(stack, item{nestIndent(it, d), it.flat, d.doc})
+		case group:
+			flat := it.flat || fits(width-col, item{it.indent, true, d.doc}, stack)
+			stack = append(stack, item{it.indent, flat, d.doc})
+		case line:
+			if it.flat && !d.hard {
+				if !d.soft {
+					out.WriteString(" ")
+					col++
+				}
+				continue
+			}
+			out.WriteString("\n")
+			lineStart = true
+			indent = it.indent
+			col = 0
+		case nil:
+		default:
+			panic("format: unexpected document")
+		}
+	}
+	return out.String()
+}
+
+// fits reports whether first, followed by the rest of the stack, fits in width columns up to the first line break
+func fits(width int, first item, stack []item) bool {
+	items := []item{first}
+	rest := len(stack)
+	for width >= 0 {
+		var it item
+		if len(items) > 0 {
+			it = items[len(items)-1]
+			items = items[:len(items)-1]
+		} else if rest > 0 {
+			rest--
+			it = stack[rest]
+		} else {
+			return true
+		}
+		switch d := it.doc.(type) {
+		case text:
+			width -= len(d)
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				items = append(items, item{it.indent, it.flat, d[i]})
+			}
+		case nest:
+			items = append(items, item{nestIndent(it, d), it.flat, d.doc})
+		case group:
+			items = append(items, item{it.indent, it.flat, d.doc})
+		case line:
+			if !it.flat || d.hard {
+				return true
+			}
+			if !d.soft {
+				width--
+			}
+		}
+	}
+	return false
+}
+
+// nestIndent returns the indentation inside the nest n of item it
+func nestIndent(it item, n nest) int {
+	if it.flat && !n.hard {
+		return it.indent
+	}
+	return it.indent + 1
+}
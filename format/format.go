@@ -0,0 +1,293 @@
+// Package format implements the canonical formatting of Monkey source
+//
+// Blocks are indented with tabs, infix operators are surrounded by spaces and every statement
+// is on its own line. Lists of call arguments and function parameters that do not fit in the
+// line width are broken one element per line. Comments and single blank lines between
+// statements are kept. Formatting formatted source gives the same source back
+package format
+
+import (
+	"math"
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/printer"
+	"monkey/token"
+	"strconv"
+	"strings"
+)
+
+// Config controls the layout of the formatted source
+type Config struct {
+	Width    int // The preferred maximum line width
+	TabWidth int // The width of the indentation tab when measuring lines
+}
+
+// DefaultConfig is the layout used by Source and by "monkey fmt"
+var DefaultConfig = Config{Width: 80, TabWidth: 4}
+
+// Source formats src with the DefaultConfig
+func Source(src []byte) ([]byte, error) {
+	return DefaultConfig.Source(src)
+}
+
+// Source formats src. If src does not parse it returns the parser.ErrorList
+func (cfg Config) Source(src []byte) ([]byte, error) {
+	program, err := parser.ParseFile("", src, parser.ParseComments|parser.AllErrors)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(cfg.Program(program)), nil
+}
+
+// Program formats a parsed program, including its comments
+func (cfg Config) Program(program *ast.Program) string {
+	return cfg.format(&formatter{comments: program.Comments}, program)
+}
+
+// Edited formats a program that was changed after it was parsed from src, such as by removing
+// statements. A blank line between two statements is only kept where src has an empty line right
+// before the second one, so the statements removed from the tree leave no gaps
+func (cfg Config) Edited(program *ast.Program, src string) string {
+	return cfg.format(&formatter{comments: program.Comments, lines: strings.Split(src, "\n")}, program)
+}
+
+func (cfg Config) format(f *formatter, program *ast.Program) string {
+	d := f.statements(program.Statements, false, token.Position{})
+	out := render(d, cfg.Width, cfg.TabWidth)
+	if out != "" {
+		out += "\n"
+	}
+	return out
+}
+
+// formatter turns a tree into a document
+// The comments not yet placed are kept in order and placed by position as the statements are formatted
+type formatter struct {
+	comments []token.Token
+	lines    []string // The lines of the source, to tell the empty ones; nil to keep every gap
+}
+
+// statements formats a list of statements, one per line, with their comments
+// In a block the comments up to end, the closing brace, are placed before it
+func (f *formatter) statements(stmts []ast.Statement, inBlock bool, end token.Position) doc {
+	out := concat{}
+	lastLine := 0 // The last source line printed so far, 0 before the first
+	emit := func(d doc, startLine, endLine int) {
+		if lastLine > 0 {
+			out = append(out, hardline)
+			if startLine > lastLine+1 && f.empty(startLine-1) {
+				// Keep a single blank line
+				out = append(out, hardline)
+			}
+		}
+		out = append(out, d)
+		lastLine = endLine
+	}
+	for i, stmt := range stmts {
+		for _, c := range f.takeComments(stmt.Pos().Offset) {
+			emit(text(c.Literal), c.Pos.Line, c.Pos.Line)
+		}
+		var next ast.Statement
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+		}
+		d := concat{f.statement(stmt, inBlock && next == nil, next)}
+		endLine := stmt.End().Line
+		if endLine < stmt.Pos().Line {
+			// A statement rewritten with code from elsewhere, such as a macro expansion, may end before it starts
+			endLine = stmt.Pos().Line
+		}
+		// Comments inside the statement and on the line it ends on trail it. A comment inside an
+		// expression has no line of its own in the output, so the first one goes at the end of the
+		// statement's last line and the others on the lines after it
+		limit := math.MaxInt32
+		if next != nil {
+			limit = next.Pos().Offset
+		} else if inBlock {
+			limit = end.Offset
+		}
+		trailed := false // Whether a comment already ends the last line of d
+		for _, c := range f.comments {
+			inside := c.Pos.Offset < stmt.End().Offset
+			if c.Pos.Offset >= limit || !inside && c.Pos.Line != endLine {
+				break
+			}
+			if !trailed && (inside || c.Pos.Line == endLine) {
+				d = append(d, text(" "), text(c.Literal))
+			} else {
+				d = append(d, hardline, text(c.Literal))
+			}
+			trailed = true
+			if c.Pos.Line > endLine {
+				endLine = c.Pos.Line
+			}
+			f.comments = f.comments[1:]
+		}
+		emit(d, stmt.Pos().Line, endLine)
+	}
+	limit := math.MaxInt32
+	if inBlock {
+		limit = end.Offset
+	}
+	for _, c := range f.takeComments(limit) {
+		emit(text(c.Literal), c.Pos.Line, c.Pos.Line)
+	}
+	return out
+}
+
+// empty reports whether the source line is empty, or whether a gap in the lines before it is kept
+func (f *formatter) empty(line int) bool {
+	if f.lines == nil {
+		return true
+	}
+	return line < 1 || line > len(f.lines) || strings.TrimSpace(f.lines[line-1]) == ""
+}
+
+// takeComments removes and returns the comments before offset
+func (f *formatter) takeComments(offset int) []token.Token {
+	i := 0
+	for i < len(f.comments) && f.comments[i].Pos.Offset < offset {
+		i++
+	}
+	taken := f.comments[:i]
+	f.comments = f.comments[i:]
+	return taken
+}
+
+// statement formats a single statement
+// The value of the last statement of a block needs no semicolon. Other expression statements
+// that end with a block need one only if next would otherwise continue them
+func (f *formatter) statement(stmt ast.Statement, last bool, next ast.Statement) doc {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		d := concat{text("let "), text(s.Name.Value)}
+		if s.Type != nil {
+			d = append(d, text(": "+s.Type.String()))
+		}
+		return append(d, text(" = "), f.expression(s.Value, token.LOWEST), text(";"))
+	case *ast.ReturnStatement:
+		return concat{text("return "), f.expression(s.ReturnValue, token.LOWEST), text(";")}
+	case *ast.ExpressionStatement:
+		var d doc
+		if s.Token.Literal == "(" && !printer.StartsWithParen(s.Expression) {
+			// Keep the paren the statement starts with, so it parses back the same
+			d = concat{text("("), f.expression(s.Expression, token.LOWEST), text(")")}
+		} else {
+			d = f.expression(s.Expression, token.LOWEST)
+		}
+		if last || endsWithBlock(s.Expression) && !continues(next) {
+			return d
+		}
+		return concat{d, text(";")}
+	case *ast.BlockStatement:
+		return f.block(s)
+	}
+	panic("format: unexpected statement")
+}
+
+func (f *formatter) block(block *ast.BlockStatement) doc {
+	body := f.statements(block.Statements, true, block.Rbrace)
+	if len(body.(concat)) == 0 {
+		return text("{}")
+	}
+	return concat{text("{"), nest{concat{hardline, body}, true}, hardline, text("}")}
+}
+
+// expression formats expr, in parentheses if its precedence is lower than the context requires
+func (f *formatter) expression(expr ast.Expression, context int) doc {
+	if printer.Precedence(expr) < context {
+		return concat{text("("), f.expression(expr, token.LOWEST), text(")")}
+	}
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return text(e.Value)
+	case *ast.IntegerLiteral:
+		return text(printer.IntegerSource(e))
+	case *ast.Boolean:
+		return text(strconv.FormatBool(e.Value))
+	case *ast.PrefixExpression:
+		return concat{text(e.Operator), f.expression(e.Right, token.PREFIX)}
+	case *ast.InfixExpression:
+		prec := printer.InfixPrecedence(e.Operator)
+		// A long expression breaks after an operator, with the rest indented
+		return group{concat{f.expression(e.Left, prec), text(" " + e.Operator), nest{concat{space, f.expression(e.Right, prec+1)}, false}}}
+	case *ast.IfExpression:
+		d := concat{text("if ("), f.expression(e.Condition, token.LOWEST), text(") "), f.block(e.Consequence)}
+		if e.Alternative != nil {
+			d = append(d, text(" else "), f.block(e.Alternative))
+		}
+		return d
+	case *ast.FunctionLiteral:
+		params := make([]doc, len(e.Parameters))
+		for i, pr := range e.Parameters {
+			params[i] = text(pr.String())
+		}
+		d := concat{text("fn"), list(params), text(" ")}
+		if e.ReturnType != nil {
+			d = append(d, text("-> "+e.ReturnType.String()+" "))
+		}
+		return append(d, f.block(e.Body))
+	case *ast.MacroLiteral:
+		params := make([]doc, len(e.Parameters))
+		for i, pr := range e.Parameters {
+			params[i] = text(pr.String())
+		}
+		return concat{text("macro"), list(params), text(" "), f.block(e.Body)}
+	case *ast.CallExpression:
+		args := make([]doc, len(e.Arguments))
+		for i, arg := range e.Arguments {
+			args[i] = f.expression(arg, token.LOWEST)
+		}
+		return concat{f.expression(e.Function, token.CALL), list(args)}
+	}
+	panic("format: unexpected expression")
+}
+
+// list formats a parenthesized, comma separated list
+// If it does not fit on the line every element goes on a line of its own
+func list(elems []doc) doc {
+	if len(elems) == 0 {
+		return text("()")
+	}
+	return group{concat{text("("), nest{concat{softline, join(elems, concat{text(","), space})}, false}, softline, text(")")}}
+}
+
+// endsWithBlock reports whether the formatted expr ends with a '}'
+func endsWithBlock(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.IfExpression, *ast.FunctionLiteral, *ast.MacroLiteral:
+		return true
+	case *ast.PrefixExpression:
+		return endsWithBlock(e.Right)
+	case *ast.InfixExpression:
+		return printer.Precedence(e.Right) > printer.InfixPrecedence(e.Operator) && endsWithBlock(e.Right)
+	}
+	return false
+}
+
+// continues reports whether stmt starts with a token that could continue the statement before it
+// if there were no semicolon in between, such as the '-' of -1 or the '(' of a grouped expression
+func continues(stmt ast.Statement) bool {
+	s, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	if s.Token.Literal == "(" || printer.StartsWithParen(s.Expression) {
+		return true
+	}
+	expr := s.Expression
+	for {
+		switch e := expr.(type) {
+		case *ast.PrefixExpression:
+			return e.Operator == "-"
+		case *ast.IntegerLiteral:
+			return e.Value < 0
+		case *ast.InfixExpression:
+			expr = e.Left
+		case *ast.CallExpression:
+			expr = e.Function
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package format
+
+import (
+	"flag"
+	"io/ioutil"
+	"monkey/parser"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata with the current formatting")
+
+// comments returns the texts of the comments of src in order
+func comments(t *testing.T, src string) []string {
+	t.Helper()
+	program, err := parser.ParseFile("", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, c := range program.Comments {
+		texts = append(texts, strings.TrimSpace(c.Literal))
+	}
+	return texts
+}
+
+// TestGolden formats every file of testdata and compares the result with the .golden file next
+// to it. Formatting the result again must not change it, and every comment must be kept in order.
+// Run the tests with -update to rewrite the golden files
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.mk"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no test files: %v", err)
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Source(src)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		golden := strings.TrimSuffix(file, ".mk") + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+		} else if want, err := ioutil.ReadFile(golden); err != nil {
+			t.Fatal(err)
+		} else if string(got) != string(want) {
+			t.Errorf("%s:\ngot\n%s\nwant\n%s", file, got, want)
+		}
+
+		again, err := Source(got)
+		if err != nil {
+			t.Fatalf("%s: the formatted source does not parse: %v", file, err)
+		}
+		if string(again) != string(got) {
+			t.Errorf("%s: formatting is not idempotent:\n%s\nthen\n%s", file, got, again)
+		}
+		if before, after := comments(t, string(src)), comments(t, string(got)); !reflect.DeepEqual(before, after) {
+			t.Errorf("%s: the comments\n%q\nbecame\n%q", file, before, after)
+		}
+	}
+}
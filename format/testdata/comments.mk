@@ -0,0 +1,19 @@
+// A comment before the first statement
+
+
+// After two blank lines, which become one
+let a = 1;   // trails the let
+let b = fn(x) {
+	// the first statement of the body
+	x + 1 // trails x + 1
+	// before the closing brace
+};
+let c = add(1, // inside the call
+	2);
+
+if (a) {
+    // an empty block with a comment
+} else { b(a) }
+let d = 3; // one
+           // two
+// at the end of the file
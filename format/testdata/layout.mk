@@ -0,0 +1,10 @@
+let   x=1+2*3;let y = (1+2)*3
+let f=fn(a,b){if(a<b){return a}else{b}}
+let g = fn(x: int, h: fn(int) -> bool) -> [int] { h(x) };
+let long = someFunction(firstArgument, secondArgument, thirdArgument, fourthArgument, fifth);
+let nested = fn(alpha, beta) { compute(alpha * 1000000, beta * 1000000, alpha + beta, alpha - beta) };
+let neg = -(-x) * !true == false;
+(2);
+(a - b) - (c - d);
+if (x) { } else { };
+let m = macro(a) { quote(unquote(a) + 1) };
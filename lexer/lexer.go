@@ -1,50 +1,146 @@
 package lexer
 
-import "monkey/token"
+import (
+	"bufio"
+	"io"
+	"monkey/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// source is what the lexer reads from. Both *strings.Reader and *bufio.Reader are sources
+type source interface {
+	io.RuneScanner
+	io.ByteReader
+}
 
 type Lexer struct {
-	input           string // The input string
-	currentPosition int    // The current position of the input
-	readPosition    int    // currentPosition + 1
-	ch              byte   // The current byte
+	src             source // Where the input is read from
+	err             error  // The first read error
+	currentPosition int    // The offset of the current character
+	readPosition    int    // The offset of the next character
+	ch              rune   // The current character
+	raw             string // The bytes of the current character
+	peek            rune   // The next character
+	peekRaw         string // The bytes of the next character
+	line            int    // The line of the current character
+	column          int    // The column of the current character, counted in bytes
+	comments        []token.Token
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return newLexer(strings.NewReader(input))
+}
+
+// NewReader is a helper function to create a Lexer that reads its input from r through a buffer
+// It produces the same tokens as New does for the whole input. Reads that end in the middle of a
+// character or of a two-character operator are handled. A read error ends the input like EOF does
+// and is reported by Err
+func NewReader(r io.Reader) *Lexer {
+	return newLexer(bufio.NewReader(r))
+}
+
+// NewAt is a helper function to create a Lexer that starts reading input at pos instead of its start
+// pos must be the position of a token or of white space outside a comment. The tokens get the
+// positions they have in the whole input, only the comments from pos on are kept
+func NewAt(input string, pos token.Position) *Lexer {
+	l := &Lexer{src: strings.NewReader(input[pos.Offset:]), line: pos.Line, column: pos.Column, readPosition: pos.Offset}
+	l.peek, l.peekRaw = l.read()
 	l.readChar()
 	return l
 }
 
+// newLexer is a helper function that creates a Lexer and reads the first character
+func newLexer(src source) *Lexer {
+	l := &Lexer{src: src, line: 1, column: 1}
+	l.peek, l.peekRaw = l.read()
+	l.readChar()
+	return l
+}
+
+// Err returns the first error other than io.EOF met while reading the input
+func (l *Lexer) Err() error {
+	return l.err
+}
+
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.column += len(l.raw)
 	}
+	l.ch, l.raw = l.peek, l.peekRaw
 	l.currentPosition = l.readPosition
-	l.readPosition += 1
+	l.readPosition += len(l.raw)
+	if l.ch != 0 {
+		l.peek, l.peekRaw = l.read()
+	}
+}
+
+// read is a helper function that reads the next character and its bytes from the source
+// It returns 0 at the end of the input. A byte that is not valid UTF-8 is read as utf8.RuneError
+// with the byte itself as its bytes, so offsets stay byte offsets into the input
+func (l *Lexer) read() (rune, string) {
+	r, size, err := l.src.ReadRune()
+	if err != nil {
+		if err != io.EOF && l.err == nil {
+			l.err = err
+		}
+		return 0, ""
+	}
+	if r == utf8.RuneError && size == 1 {
+		l.src.UnreadRune()
+		b, _ := l.src.ReadByte()
+		return r, string([]byte{b})
+	}
+	return r, string(r)
+}
+
+// position returns the position of the current character
+func (l *Lexer) position() token.Position {
+	return token.Position{Offset: l.currentPosition, Line: l.line, Column: l.column}
 }
 
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 	l.skipWhiteSpace()
+	for l.ch == '/' && l.peekChar() == '/' {
+		l.readComment()
+		l.skipWhiteSpace()
+	}
+	pos := l.position()
+	tok.Pos = pos
 	switch l.ch {
 	case ';':
-		tok = newToken(token.SEMICOLON, l.ch)
+		tok = newToken(token.SEMICOLON, l.raw)
 	case '(':
-		tok = newToken(token.LPAREN, l.ch)
+		tok = newToken(token.LPAREN, l.raw)
 	case ')':
-		tok = newToken(token.RPAREN, l.ch)
+		tok = newToken(token.RPAREN, l.raw)
 	case ',':
-		tok = newToken(token.COMMA, l.ch)
+		tok = newToken(token.COMMA, l.raw)
+	case ':':
+		tok = newToken(token.COLON, l.raw)
+	case '[':
+		tok = newToken(token.LBRACKET, l.raw)
+	case ']':
+		tok = newToken(token.RBRACKET, l.raw)
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = newToken(token.PLUS, l.raw)
 	case '{':
-		tok = newToken(token.LBRACE, l.ch)
+		tok = newToken(token.LBRACE, l.raw)
 	case '}':
-		tok = newToken(token.RBRACE, l.ch)
+		tok = newToken(token.RBRACE, l.raw)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '>' {
+			l.readChar()
+			l.readChar()
+			tok.Literal = "->"
+			tok.Type = token.ARROW
+			return tok
+		}
+		tok = newToken(token.MINUS, l.raw)
 	case '!':
 		if l.peekChar() == '=' {
 			l.readChar()
@@ -53,7 +149,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.NOT_EQ
 			return tok
 		}
-		tok = newToken(token.BANG, l.ch)
+		tok = newToken(token.BANG, l.raw)
 	case '=':
 		if l.peekChar() == '=' {
 			l.readChar()
@@ -62,18 +158,19 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.EQ
 			return tok
 		}
-		tok = newToken(token.ASSIGN, l.ch)
+		tok = newToken(token.ASSIGN, l.raw)
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = newToken(token.SLASH, l.raw)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = newToken(token.ASTERISK, l.raw)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		tok = newToken(token.LT, l.raw)
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		tok = newToken(token.GT, l.raw)
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
+		return tok
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
@@ -84,20 +181,44 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.INT
 			return tok
 		}
-		tok = newToken(token.ILLEGAL, l.ch)
+		tok = newToken(token.ILLEGAL, l.raw)
 	}
+	tok.Pos = pos
 	l.readChar()
 	return tok
 }
 
 // readIdentifier is a helper function that reads all the characters until it encounters a character that is not a letter
 func (l *Lexer) readIdentifier() string {
-	position := l.currentPosition
+	var literal strings.Builder
 	// Continue reading until you encounter a non-letter character
 	for isLetter(l.ch) {
+		literal.WriteString(l.raw)
 		l.readChar()
 	}
-	return l.input[position:l.currentPosition]
+	return literal.String()
+}
+
+// Comments returns the comments read so far, in source order
+// A comment starts with // and runs to the end of the line
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// readComment is a helper function that reads a comment up to the end of the line and keeps it aside
+func (l *Lexer) readComment() {
+	pos := l.position()
+	var b strings.Builder
+	for l.ch != '\n' && l.ch != 0 {
+		b.WriteString(l.raw)
+		l.readChar()
+	}
+	literal := b.String()
+	// Leave a trailing carriage return to the white space
+	if len(literal) > 0 && literal[len(literal)-1] == '\r' {
+		literal = literal[:len(literal)-1]
+	}
+	l.comments = append(l.comments, token.Token{Type: token.COMMENT, Literal: literal, Pos: pos})
 }
 
 // skipWhiteSpace is a helper function to remove all the whiteSpaces
@@ -107,35 +228,33 @@ func (l *Lexer) skipWhiteSpace() {
 	}
 }
 
-// isLetter is a helper function that checks if the byte is a letter
-func isLetter(ch byte) bool {
+// isLetter is a helper function that checks if the character is a letter
+func isLetter(ch rune) bool {
 	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
 }
 
 // readNumber is a helper function that reads all the bytes until it encounters a non number
 func (l *Lexer) readNumber() string {
-	position := l.currentPosition
+	var literal strings.Builder
 	// read all the digits
 	for isDigit(l.ch) {
+		literal.WriteString(l.raw)
 		l.readChar()
 	}
-	return l.input[position:l.currentPosition]
+	return literal.String()
 }
 
-// isDigit is a helper function to check if the byte is a digit
-func isDigit(ch byte) bool {
+// isDigit is a helper function to check if the character is a digit
+func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
 
 // newToken is a helper function to return a  new token.Token
-func newToken(tokenType token.TokenType, ch byte) token.Token {
-	return token.Token{Type: tokenType, Literal: string(ch)}
+func newToken(tokenType token.TokenType, literal string) token.Token {
+	return token.Token{Type: tokenType, Literal: literal}
 }
 
 // peekChar is a helper function that returns the next char
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
-	}
-	return l.input[l.readPosition]
+func (l *Lexer) peekChar() rune {
+	return l.peek
 }
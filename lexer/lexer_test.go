@@ -0,0 +1,113 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"monkey/token"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// input has two-character operators, characters of several bytes, bytes that are not UTF-8,
+// comments and line endings at every place a read could split them
+const input = "let x = fn(a: int) -> bool { a == 1 != !b; };\r\n" +
+	"// é and → in a comment\n" +
+	"x(é, 10 <= 2) → 🙈;\xff\xfe=\xe2\x86 ==\n" +
+	"if(a!=b){return-a->b}else{a/b*c==!d}//end"
+
+// chunkReader returns at most n bytes per Read
+type chunkReader struct {
+	r io.Reader
+	n int
+}
+
+func (c chunkReader) Read(p []byte) (int, error) {
+	if len(p) > c.n {
+		p = p[:c.n]
+	}
+	return c.r.Read(p)
+}
+
+// tokens returns all the tokens of l up to and including EOF
+func tokens(l *Lexer) []token.Token {
+	var toks []token.Token
+	for {
+		tok := l.NextToken()
+		toks = append(toks, tok)
+		if tok.Type == token.EOF {
+			return toks
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	l := New(input)
+	want := tokens(l)
+	readers := map[string]func() io.Reader{
+		"whole":              func() io.Reader { return strings.NewReader(input) },
+		"one byte":           func() io.Reader { return iotest.OneByteReader(strings.NewReader(input)) },
+		"half":               func() io.Reader { return iotest.HalfReader(strings.NewReader(input)) },
+		"last data with EOF": func() io.Reader { return iotest.DataErrReader(strings.NewReader(input)) },
+	}
+	for n := 2; n <= 7; n++ {
+		n := n
+		readers[fmt.Sprintf("chunks of %d", n)] = func() io.Reader { return chunkReader{strings.NewReader(input), n} }
+	}
+	for name, r := range readers {
+		rl := NewReader(r())
+		if got := tokens(rl); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\ngot  %v\nwant %v", name, got, want)
+		}
+		if !reflect.DeepEqual(rl.Comments(), l.Comments()) {
+			t.Errorf("%s: the comments are %v, want %v", name, rl.Comments(), l.Comments())
+		}
+		if rl.Err() != nil {
+			t.Errorf("%s: %v", name, rl.Err())
+		}
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	failure := errors.New("disk on fire")
+	l := NewReader(io.MultiReader(strings.NewReader("let x = 1"), iotest.ErrReader(failure)))
+	var literals []string
+	for _, tok := range tokens(l) {
+		literals = append(literals, tok.Literal)
+	}
+	// The error ends the input like EOF does
+	if want := []string{"let", "x", "=", "1", ""}; !reflect.DeepEqual(literals, want) {
+		t.Errorf("got the tokens %q, want %q", literals, want)
+	}
+	if l.Err() != failure {
+		t.Errorf("Err() = %v, want %v", l.Err(), failure)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	l := New("let é = 1;\n\tx\xff==")
+	want := []struct {
+		typ     token.TokenType
+		literal string
+		pos     token.Position
+	}{
+		{token.LET, "let", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.ILLEGAL, "é", token.Position{Offset: 4, Line: 1, Column: 5}},
+		// Columns count bytes
+		{token.ASSIGN, "=", token.Position{Offset: 7, Line: 1, Column: 8}},
+		{token.INT, "1", token.Position{Offset: 9, Line: 1, Column: 10}},
+		{token.SEMICOLON, ";", token.Position{Offset: 10, Line: 1, Column: 11}},
+		{token.IDENT, "x", token.Position{Offset: 13, Line: 2, Column: 2}},
+		{token.ILLEGAL, "\xff", token.Position{Offset: 14, Line: 2, Column: 3}},
+		{token.EQ, "==", token.Position{Offset: 15, Line: 2, Column: 4}},
+		{token.EOF, "", token.Position{Offset: 17, Line: 2, Column: 6}},
+	}
+	for i, w := range want {
+		tok := l.NextToken()
+		if tok.Type != w.typ || tok.Literal != w.literal || tok.Pos != w.pos {
+			t.Errorf("token %d: got %s %q at %+v, want %s %q at %+v", i, tok.Type, tok.Literal, tok.Pos, w.typ, w.literal, w.pos)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package lexer
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+// Tokenize returns all the tokens of src, ending with the EOF token
+// Every ILLEGAL token is also reported as an error, the tokens after it are still returned
+func Tokenize(src string) ([]token.Token, []error) {
+	var tokens []token.Token
+	var errs []error
+	l := New(src)
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.ILLEGAL {
+			errs = append(errs, fmt.Errorf("%s: illegal character %q", tok.Pos, tok.Literal))
+		}
+		if tok.Type == token.EOF {
+			return tokens, errs
+		}
+	}
+}
+
+// Iterator steps through the tokens of a Lexer up to, but not including, the EOF token
+//
+//	it := lexer.New(src).Iterator()
+//	for it.Next() {
+//		tok := it.Token()
+//		...
+//	}
+type Iterator struct {
+	l   *Lexer
+	tok token.Token
+}
+
+// Iterator returns an Iterator over the tokens not yet read from l
+func (l *Lexer) Iterator() *Iterator {
+	return &Iterator{l: l}
+}
+
+// Next reads the next token. It returns false when the EOF token is reached
+func (it *Iterator) Next() bool {
+	if it.tok.Type == token.EOF {
+		return false
+	}
+	it.tok = it.l.NextToken()
+	return it.tok.Type != token.EOF
+}
+
+// Token returns the token read by the last call to Next
+func (it *Iterator) Token() token.Token {
+	return it.tok
+}
@@ -0,0 +1,55 @@
+package lexer
+
+import (
+	"monkey/token"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, errs := Tokenize("let a = 1 @ b; # ")
+	var got []string
+	for _, tok := range tokens {
+		got = append(got, string(tok.Type)+" "+tok.Literal)
+	}
+	want := []string{"LET let", "IDENT a", "= =", "INT 1", "ILLEGAL @", "IDENT b", "; ;", "ILLEGAL #", "EOF "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got the tokens\n%q\nwant\n%q", got, want)
+	}
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	if want := []string{`1:11: illegal character "@"`, `1:16: illegal character "#"`}; !reflect.DeepEqual(msgs, want) {
+		t.Errorf("got the errors %q, want %q", msgs, want)
+	}
+
+	tokens, errs = Tokenize("")
+	if len(tokens) != 1 || tokens[0].Type != token.EOF || errs != nil {
+		t.Errorf("an empty source: got %v, %v; want a single EOF token", tokens, errs)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	src := "fn(x) { x == 1 } // end"
+	all, _ := Tokenize(src)
+	var got []token.Token
+	it := New(src).Iterator()
+	for it.Next() {
+		got = append(got, it.Token())
+	}
+	// The iterator stops before EOF and stays stopped
+	if !reflect.DeepEqual(got, all[:len(all)-1]) {
+		t.Errorf("got\n%v\nwant\n%v", got, all[:len(all)-1])
+	}
+	if it.Next() || it.Token().Type != token.EOF {
+		t.Errorf("Next after EOF returned true or the token %v", it.Token())
+	}
+
+	// The iterator continues from where the lexer is
+	l := New("let x = 1;")
+	l.NextToken()
+	if it := l.Iterator(); !it.Next() || it.Token().Literal != "x" {
+		t.Errorf("the iterator starts at %v, want x", it.Token())
+	}
+}
@@ -0,0 +1,121 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Client is a JSON-RPC client for a language server
+// It is used to drive a Server in the same process, see Pipe
+type Client struct {
+	conn    *conn
+	mu      sync.Mutex
+	nextID  int
+	pending map[string]chan *message // The requests waiting for their response, by id
+	done    chan struct{}            // Closed when the connection ends
+	err     error                    // Why the connection ended
+
+	// Notifications receives the notifications of the server, such as textDocument/publishDiagnostics
+	// It must be drained, the client stops reading responses while it is full
+	Notifications chan Notification
+}
+
+// Notification is a notification sent by the server
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+// NewClient is a helper function to create a Client that talks to a server through r and w
+func NewClient(r io.Reader, w io.Writer) *Client {
+	c := &Client{
+		conn:          newConn(r, w),
+		pending:       map[string]chan *message{},
+		done:          make(chan struct{}),
+		Notifications: make(chan Notification, 64),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Pipe starts s on one end of an in-process connection and returns a Client for the other end
+// The returned channel receives the result of Serve when the server stops
+func Pipe(s *Server) (*Client, <-chan error) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		err := s.Serve(serverR, serverW)
+		serverW.Close()
+		served <- err
+	}()
+	return NewClient(clientR, clientW), served
+}
+
+// Call sends a request and waits for its response. The result is unmarshaled into result if it is not nil
+// A failed request returns a *ResponseError
+func (c *Client) Call(method string, params, result interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	ch := make(chan *message, 1)
+	c.pending[string(id)] = ch
+	c.mu.Unlock()
+	if err := c.conn.write(&message{ID: &id, Method: method, Params: data}); err != nil {
+		return err
+	}
+	select {
+	case m := <-ch:
+		if m.Error != nil {
+			return m.Error
+		}
+		if result != nil && len(m.Result) > 0 {
+			return json.Unmarshal(m.Result, result)
+		}
+		return nil
+	case <-c.done:
+		return c.err
+	}
+}
+
+// Notify sends a notification
+func (c *Client) Notify(method string, params interface{}) error {
+	return c.conn.notify(method, params)
+}
+
+// readLoop is a helper function that hands the messages of the server to Call and Notifications
+func (c *Client) readLoop() {
+	defer close(c.Notifications)
+	for {
+		m, err := c.conn.read()
+		if err != nil {
+			if err == io.EOF {
+				err = errors.New("jsonrpc: connection closed")
+			}
+			c.err = err
+			close(c.done)
+			return
+		}
+		if m.isRequest() {
+			c.Notifications <- Notification{Method: m.Method, Params: m.Params}
+			continue
+		}
+		if m.ID == nil {
+			continue
+		}
+		c.mu.Lock()
+		ch := c.pending[string(*m.ID)]
+		delete(c.pending, string(*m.ID))
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- m
+		}
+	}
+}
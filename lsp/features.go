@@ -0,0 +1,203 @@
+package lsp
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/format"
+	"monkey/lexer"
+	"monkey/resolver"
+	"monkey/token"
+	"strings"
+)
+
+func (s *Server) hover(params *TextDocumentPositionParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	m := d.lines()
+	node := nodeAt(d.doc.Program(), m.offset(params.Position))
+	if node == nil {
+		return nil, nil
+	}
+	kind := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	var value string
+	switch n := node.(type) {
+	case *ast.Identifier:
+		value = fmt.Sprintf("**%s** `%s`\n\n%s", kind, n.Value, d.describeBinding(n))
+	case *ast.IntegerLiteral:
+		value = fmt.Sprintf("**%s** `%d`", kind, n.Value)
+	case *ast.Boolean:
+		value = fmt.Sprintf("**%s** `%t`", kind, n.Value)
+	case *ast.PrefixExpression:
+		value = fmt.Sprintf("**%s** `%s`", kind, n.Operator)
+	case *ast.InfixExpression:
+		value = fmt.Sprintf("**%s** `%s`", kind, n.Operator)
+	case *ast.FunctionLiteral:
+		value = fmt.Sprintf("**%s** with %d parameters", kind, len(n.Parameters))
+	case *ast.MacroLiteral:
+		value = fmt.Sprintf("**%s** with %d parameters", kind, len(n.Parameters))
+	case *ast.CallExpression:
+		value = fmt.Sprintf("**%s** with %d arguments", kind, len(n.Arguments))
+	default:
+		value = fmt.Sprintf("**%s**", kind)
+	}
+	r := m.tokenRange(node.Pos(), node.End())
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: value}, Range: &r}, nil
+}
+
+// describeBinding returns what binds the identifier id, for hover
+func (d *document) describeBinding(id *ast.Identifier) string {
+	sym := d.resolve().SymbolOf(id)
+	if sym == nil {
+		return "not bound in this document"
+	}
+	how := "bound by `let`"
+	if sym.Kind == resolver.ParamSymbol {
+		how = "parameter"
+	}
+	def := sym.Decl
+	if def == id {
+		return fmt.Sprintf("%s, defined here", how)
+	}
+	return fmt.Sprintf("%s at line %d, column %d", how, def.Pos().Line, def.Pos().Column)
+}
+
+func (s *Server) definition(params *TextDocumentPositionParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	m := d.lines()
+	id, ok := nodeAt(d.doc.Program(), m.offset(params.Position)).(*ast.Identifier)
+	if !ok {
+		return nil, nil
+	}
+	sym := d.resolve().SymbolOf(id)
+	if sym == nil {
+		return nil, nil
+	}
+	// The name only, without the type annotation of a parameter
+	return &Location{URI: d.uri, Range: m.tokenRange(sym.Decl.Pos(), sym.Decl.Token.End())}, nil
+}
+
+// documentSymbol returns the top level let statements
+func (s *Server) documentSymbol(params *DocumentSymbolParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	m := d.lines()
+	symbols := []DocumentSymbol{}
+	for _, stmt := range d.doc.Program().Statements {
+		ls, ok := stmt.(*ast.LetStatement)
+		if !ok || ls.Name == nil {
+			continue
+		}
+		kind := SymbolKindVariable
+		detail := ""
+		if fn, ok := ls.Value.(*ast.FunctionLiteral); ok {
+			kind = SymbolKindFunction
+			params := make([]string, len(fn.Parameters))
+			for i, p := range fn.Parameters {
+				params[i] = p.Value
+			}
+			detail = "fn(" + strings.Join(params, ", ") + ")"
+		}
+		if m, ok := ls.Value.(*ast.MacroLiteral); ok {
+			kind = SymbolKindFunction
+			params := make([]string, len(m.Parameters))
+			for i, p := range m.Parameters {
+				params[i] = p.Value
+			}
+			detail = "macro(" + strings.Join(params, ", ") + ")"
+		}
+		symbols = append(symbols, DocumentSymbol{
+			Name:           ls.Name.Value,
+			Detail:         detail,
+			Kind:           kind,
+			Range:          m.tokenRange(ls.Pos(), ls.End()),
+			SelectionRange: m.tokenRange(ls.Name.Pos(), ls.Name.End()),
+		})
+	}
+	return symbols, nil
+}
+
+// foldingRange returns a range for every block with lines between its braces
+func (s *Server) foldingRange(params *FoldingRangeParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	ranges := []FoldingRange{}
+	folded := map[int]bool{} // The lines that start a range, only the outermost block of a line folds
+	ast.Inspect(d.doc.Program(), func(n ast.Node) bool {
+		if b, ok := n.(*ast.BlockStatement); ok && b.Rbrace.IsValid() && b.Rbrace.Line > b.Token.Pos.Line+1 && !folded[b.Token.Pos.Line] {
+			// The closing line stays visible
+			ranges = append(ranges, FoldingRange{StartLine: b.Token.Pos.Line - 1, EndLine: b.Rbrace.Line - 2})
+			folded[b.Token.Pos.Line] = true
+		}
+		return n != nil
+	})
+	return ranges, nil
+}
+
+// formatting replaces the whole document with its formatted source
+// A document with errors is not formatted
+func (s *Server) formatting(params *DocumentFormattingParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if d.doc.Err() != nil {
+		return nil, nil
+	}
+	cfg := format.DefaultConfig
+	if params.Options.TabSize > 0 {
+		cfg.TabWidth = params.Options.TabSize
+	}
+	src := d.doc.Source()
+	out := cfg.Program(d.doc.Program())
+	if out == src {
+		return []TextEdit{}, nil
+	}
+	return []TextEdit{{Range: d.lines().rangeOf(0, len(src)), NewText: out}}, nil
+}
+
+// tokenEnd is a helper function that returns the end offset of the token at pos in src
+func tokenEnd(src string, pos token.Position) int {
+	if pos.Offset >= len(src) {
+		return len(src)
+	}
+	return lexer.NewAt(src, pos).NextToken().End().Offset
+}
+
+// nodeAt is a helper function that returns the innermost node at offset
+// An identifier also counts as being at the offset just after it, where the cursor is after typing it
+func nodeAt(program *ast.Program, offset int) ast.Node {
+	var found ast.Node
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		if _, ok := n.(*ast.Program); ok {
+			return true
+		}
+		start, end := n.Pos().Offset, n.End().Offset
+		_, isIdent := n.(*ast.Identifier)
+		if start <= offset && (offset < end || isIdent && offset == end) {
+			found = n
+			return true
+		}
+		return false
+	})
+	return found
+}
+
+// resolve returns the symbol table of the current tree, built once per tree
+func (d *document) resolve() *resolver.Info {
+	if d.info == nil {
+		d.info = resolver.Resolve(d.doc.Program())
+	}
+	return d.info
+}
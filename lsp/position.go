@@ -0,0 +1,81 @@
+package lsp
+
+import (
+	"monkey/token"
+	"sort"
+	"unicode/utf8"
+)
+
+// mapper converts between byte offsets in a source and LSP positions, which count UTF-16 code units
+type mapper struct {
+	src   string
+	lines []int // The offset of the start of every line
+}
+
+func newMapper(src string) *mapper {
+	m := &mapper{src: src, lines: []int{0}}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			m.lines = append(m.lines, i+1)
+		}
+	}
+	return m
+}
+
+// position returns the LSP position of a byte offset
+func (m *mapper) position(offset int) Position {
+	if offset > len(m.src) {
+		offset = len(m.src)
+	}
+	line := sort.Search(len(m.lines), func(i int) bool { return m.lines[i] > offset }) - 1
+	return Position{Line: line, Character: utf16Len(m.src[m.lines[line]:offset])}
+}
+
+// rangeOf returns the LSP range of the byte offsets from start up to end
+func (m *mapper) rangeOf(start, end int) Range {
+	return Range{Start: m.position(start), End: m.position(end)}
+}
+
+// tokenRange returns the LSP range of the byte offsets between two token positions
+func (m *mapper) tokenRange(start, end token.Position) Range {
+	return m.rangeOf(start.Offset, end.Offset)
+}
+
+// offset returns the byte offset of an LSP position
+// Positions past the end of a line or of the source are moved back to the end
+func (m *mapper) offset(p Position) int {
+	if p.Line < 0 {
+		return 0
+	}
+	if p.Line >= len(m.lines) {
+		return len(m.src)
+	}
+	offset := m.lines[p.Line]
+	end := len(m.src)
+	if p.Line+1 < len(m.lines) {
+		end = m.lines[p.Line+1] - 1
+	}
+	for units := 0; offset < end && units < p.Character; {
+		r, size := utf8.DecodeRuneInString(m.src[offset:end])
+		units += utf16Units(r)
+		offset += size
+	}
+	return offset
+}
+
+// utf16Len is a helper function that returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16Units(r)
+	}
+	return n
+}
+
+// utf16Units is a helper function that returns the number of UTF-16 code units of r
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
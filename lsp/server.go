@@ -0,0 +1,217 @@
+// Package lsp implements a Language Server Protocol server for Monkey
+//
+// The server speaks JSON-RPC over a reader and a writer, usually the standard input and output of
+// "monkey lsp". It publishes the parser errors of open documents as diagnostics, and answers hover,
+// go to definition, document symbol, folding range and formatting requests. Documents are kept as
+// parser.Documents, so an edit only reparses what it changes
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/parser"
+	"monkey/resolver"
+)
+
+// Server is a language server. Its methods are not safe for concurrent use, Serve handles one message at a time
+type Server struct {
+	conn     *conn
+	docs     map[string]*document
+	shutdown bool // Set by the shutdown request, after which only exit is expected
+}
+
+// document is an open text document
+type document struct {
+	uri     string
+	version int
+	doc     *parser.Document
+	mapper  *mapper        // Built on demand for the current source
+	info    *resolver.Info // Built on demand for the current tree
+}
+
+// NewServer is a helper function to create a new Server
+func NewServer() *Server {
+	return &Server{docs: map[string]*document{}}
+}
+
+// Serve reads messages from in and writes the responses and notifications to out
+// It returns nil after the exit notification or when in ends, and an error if a message cannot be read
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.conn = newConn(in, out)
+	for {
+		m, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if rerr, ok := err.(*ResponseError); ok {
+			// A message that is not JSON is answered with a null id, the next one may be fine
+			null := json.RawMessage("null")
+			s.conn.reply(&null, nil, rerr)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !m.isRequest() {
+			// Responses to requests of the server; it sends none
+			continue
+		}
+		if m.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(m)
+		if m.ID != nil {
+			if err := s.conn.reply(m.ID, result, err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle dispatches a request or a notification to its handler
+func (s *Server) handle(m *message) (interface{}, error) {
+	if s.shutdown {
+		return nil, &ResponseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+	switch m.Method {
+	case "initialize":
+		return s.initialize()
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		return decode(m, &params, func() (interface{}, error) { return nil, s.didOpen(&params) })
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		return decode(m, &params, func() (interface{}, error) { return nil, s.didChange(&params) })
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		return decode(m, &params, func() (interface{}, error) { return nil, s.didClose(&params) })
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		return decode(m, &params, func() (interface{}, error) { return s.hover(&params) })
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		return decode(m, &params, func() (interface{}, error) { return s.definition(&params) })
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		return decode(m, &params, func() (interface{}, error) { return s.documentSymbol(&params) })
+	case "textDocument/foldingRange":
+		var params FoldingRangeParams
+		return decode(m, &params, func() (interface{}, error) { return s.foldingRange(&params) })
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		return decode(m, &params, func() (interface{}, error) { return s.formatting(&params) })
+	}
+	return nil, &ResponseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", m.Method)}
+}
+
+// decode is a helper function that unmarshals the params of m into params and then calls f
+func decode(m *message, params interface{}, f func() (interface{}, error)) (interface{}, error) {
+	if err := json.Unmarshal(m.Params, params); err != nil {
+		return nil, &ResponseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return f()
+}
+
+func (s *Server) initialize() (interface{}, error) {
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           syncIncremental,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			DocumentSymbolProvider:     true,
+			FoldingRangeProvider:       true,
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: &ServerInfo{Name: "monkey"},
+	}, nil
+}
+
+func (s *Server) didOpen(params *DidOpenTextDocumentParams) error {
+	item := params.TextDocument
+	d := &document{uri: item.URI, version: item.Version, doc: parser.NewDocument(item.Text)}
+	s.docs[item.URI] = d
+	return s.publishDiagnostics(d)
+}
+
+func (s *Server) didChange(params *DidChangeTextDocumentParams) error {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	for _, change := range params.ContentChanges {
+		if change.Range == nil {
+			d.doc = parser.NewDocument(change.Text)
+		} else {
+			m := d.lines()
+			edit := parser.Edit{Start: m.offset(change.Range.Start), End: m.offset(change.Range.End), Text: change.Text}
+			if err := d.doc.Apply(edit); err != nil {
+				return &ResponseError{Code: codeInvalidParams, Message: err.Error()}
+			}
+		}
+		d.mapper, d.info = nil, nil
+	}
+	d.version = params.TextDocument.Version
+	return s.publishDiagnostics(d)
+}
+
+func (s *Server) didClose(params *DidCloseTextDocumentParams) error {
+	delete(s.docs, params.TextDocument.URI)
+	// Clear the diagnostics of the closed document
+	return s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+}
+
+// document returns the open document with the given uri
+func (s *Server) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &ResponseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %s is not open", uri)}
+	}
+	return d, nil
+}
+
+// lines returns the mapper of the current source of d
+func (d *document) lines() *mapper {
+	if d.mapper == nil {
+		d.mapper = newMapper(d.doc.Source())
+	}
+	return d.mapper
+}
+
+// publishDiagnostics sends the parser errors of d to the client
+// A document that parses is also checked for undefined names, duplicate parameters and shadowing
+func (s *Server) publishDiagnostics(d *document) error {
+	diagnostics := []Diagnostic{}
+	if d.doc.Err() == nil {
+		m := d.lines()
+		for _, p := range d.resolve().Problems {
+			severity := SeverityError
+			if !p.IsError() {
+				severity = SeverityWarning
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    m.tokenRange(p.Pos(), p.End()),
+				Severity: severity,
+				Source:   "monkey",
+				Message:  p.Msg,
+			})
+		}
+	}
+	if list, ok := d.doc.Err().(parser.ErrorList); ok {
+		m := d.lines()
+		for _, e := range list {
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    m.rangeOf(e.Pos.Offset, tokenEnd(d.doc.Source(), e.Pos)),
+				Severity: SeverityError,
+				Source:   "monkey",
+				Message:  e.Msg,
+			})
+		}
+	}
+	return s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: d.uri, Version: d.version, Diagnostics: diagnostics})
+}
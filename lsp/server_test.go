@@ -0,0 +1,281 @@
+package lsp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const uri = "file:///test.mk"
+
+// source is the document the tests open
+const source = `let add = fn(a, b) {
+	a + b
+};
+let x = add(1, 2);
+// é
+let y = fn(n) { if (n) {
+ y(n)
+} };
+`
+
+// session is a server driven through a Client
+type session struct {
+	t      *testing.T
+	client *Client
+	served <-chan error
+}
+
+// start starts a server, initializes it and opens text as the document uri
+func start(t *testing.T, text string) *session {
+	t.Helper()
+	client, served := Pipe(NewServer())
+	s := &session{t: t, client: client, served: served}
+	var result InitializeResult
+	s.call("initialize", map[string]interface{}{}, &result)
+	if !result.Capabilities.HoverProvider || result.Capabilities.TextDocumentSync != 2 {
+		t.Fatalf("initialize: unexpected capabilities %+v", result.Capabilities)
+	}
+	s.notify("initialized", map[string]interface{}{})
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "monkey", Version: 1, Text: text}})
+	return s
+}
+
+// stop shuts the server down and checks that it stops cleanly
+func (s *session) stop() {
+	s.t.Helper()
+	s.call("shutdown", nil, nil)
+	s.notify("exit", nil)
+	select {
+	case err := <-s.served:
+		if err != nil {
+			s.t.Fatalf("Serve returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		s.t.Fatal("the server did not stop after exit")
+	}
+}
+
+func (s *session) call(method string, params, result interface{}) {
+	s.t.Helper()
+	if err := s.client.Call(method, params, result); err != nil {
+		s.t.Fatalf("%s: %v", method, err)
+	}
+}
+
+func (s *session) notify(method string, params interface{}) {
+	s.t.Helper()
+	if err := s.client.Notify(method, params); err != nil {
+		s.t.Fatalf("%s: %v", method, err)
+	}
+}
+
+// diagnostics waits for the next diagnostics the server publishes
+func (s *session) diagnostics() PublishDiagnosticsParams {
+	s.t.Helper()
+	select {
+	case n := <-s.client.Notifications:
+		if n.Method != "textDocument/publishDiagnostics" {
+			s.t.Fatalf("got notification %s, want textDocument/publishDiagnostics", n.Method)
+		}
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(n.Params, &params); err != nil {
+			s.t.Fatal(err)
+		}
+		return params
+	case <-time.After(5 * time.Second):
+		s.t.Fatal("no diagnostics published")
+	}
+	return PublishDiagnosticsParams{}
+}
+
+// change sends an edit of the range from start to end
+func (s *session) change(version int, start, end Position, text string) {
+	s.t.Helper()
+	s.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: version},
+		ContentChanges: []TextDocumentContentChangeEvent{{Range: &Range{Start: start, End: end}, Text: text}},
+	})
+}
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: character}}
+}
+
+func TestDiagnostics(t *testing.T) {
+	s := start(t, "let z = 1 +;\n")
+	d := s.diagnostics()
+	want := []Diagnostic{{
+		Range:    Range{Start: Position{0, 11}, End: Position{0, 12}},
+		Severity: SeverityError,
+		Source:   "monkey",
+		Message:  "No prefix parsing function for ; found",
+	}}
+	if d.URI != uri || d.Version != 1 || !reflect.DeepEqual(d.Diagnostics, want) {
+		t.Errorf("didOpen: got %+v, want the diagnostics %+v", d, want)
+	}
+
+	// Replacing the whole text fixes the error
+	s.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let z = 1 + 2;\n"}},
+	})
+	if d := s.diagnostics(); d.Version != 2 || len(d.Diagnostics) != 0 {
+		t.Errorf("didChange: got %+v, want no diagnostics", d)
+	}
+
+	// And an edit of a range brings it back
+	s.change(3, Position{0, 11}, Position{0, 13}, "")
+	if d := s.diagnostics(); d.Version != 3 || !reflect.DeepEqual(d.Diagnostics, want) {
+		t.Errorf("incremental didChange: got %+v, want the diagnostics %+v", d, want)
+	}
+	s.stop()
+}
+
+func TestIncrementalChange(t *testing.T) {
+	s := start(t, source)
+	s.diagnostics()
+	var edits []TextEdit
+	formatting := DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: uri}, Options: FormattingOptions{TabSize: 4}}
+
+	// Positions count UTF-16 code units, so the é of the comment counts as one
+	s.change(2, Position{4, 4}, Position{4, 4}, " and è")
+	s.diagnostics()
+	s.change(3, Position{3, 15}, Position{3, 16}, "40")
+	s.change(4, Position{8, 0}, Position{8, 0}, "let z = x;\n")
+	s.diagnostics()
+	if d := s.diagnostics(); d.Version != 4 || len(d.Diagnostics) != 0 {
+		t.Errorf("got %+v, want no diagnostics", d)
+	}
+	s.call("textDocument/formatting", formatting, &edits)
+	if len(edits) != 1 {
+		t.Fatalf("got %d edits, want 1", len(edits))
+	}
+	for _, want := range []string{"// é and è\n", "let x = add(1, 40);\n", "let z = x;\n"} {
+		if !strings.Contains(edits[0].NewText, want) {
+			t.Errorf("the edited document %q does not contain %q", edits[0].NewText, want)
+		}
+	}
+
+	// The definitions follow the edits
+	var loc *Location
+	s.call("textDocument/definition", at(8, 8), &loc)
+	if want := (Range{Position{3, 4}, Position{3, 5}}); loc == nil || loc.Range != want {
+		t.Errorf("definition of z's value: got %+v, want %+v", loc, want)
+	}
+	s.stop()
+}
+
+func TestHoverAndDefinition(t *testing.T) {
+	s := start(t, source)
+	s.diagnostics()
+	tests := []struct {
+		name       string
+		pos        TextDocumentPositionParams
+		hover      string
+		definition *Range
+	}{
+		{
+			name:       "let",
+			pos:        at(3, 9),
+			hover:      "**Identifier** `add`\n\nbound by `let` at line 1, column 5",
+			definition: &Range{Position{0, 4}, Position{0, 7}},
+		},
+		{
+			name:       "parameter",
+			pos:        at(1, 1),
+			hover:      "**Identifier** `a`\n\nparameter at line 1, column 14",
+			definition: &Range{Position{0, 13}, Position{0, 14}},
+		},
+		{
+			name:       "recursive let",
+			pos:        at(6, 1),
+			hover:      "**Identifier** `y`\n\nbound by `let` at line 6, column 5",
+			definition: &Range{Position{5, 4}, Position{5, 5}},
+		},
+		{
+			name:  "operator",
+			pos:   at(1, 3),
+			hover: "**InfixExpression** `+`",
+		},
+		{
+			name: "comment",
+			pos:  at(4, 3),
+		},
+	}
+	for _, tt := range tests {
+		var hover *Hover
+		s.call("textDocument/hover", tt.pos, &hover)
+		switch {
+		case tt.hover == "" && hover != nil:
+			t.Errorf("%s: got hover %q, want none", tt.name, hover.Contents.Value)
+		case tt.hover != "" && (hover == nil || hover.Contents.Value != tt.hover):
+			t.Errorf("%s: got hover %+v, want %q", tt.name, hover, tt.hover)
+		}
+
+		var loc *Location
+		s.call("textDocument/definition", tt.pos, &loc)
+		switch {
+		case tt.definition == nil && loc != nil:
+			t.Errorf("%s: got definition %+v, want none", tt.name, loc)
+		case tt.definition != nil && (loc == nil || loc.URI != uri || loc.Range != *tt.definition):
+			t.Errorf("%s: got definition %+v, want %+v", tt.name, loc, *tt.definition)
+		}
+	}
+	s.stop()
+}
+
+func TestDocumentSymbolFoldingAndFormatting(t *testing.T) {
+	s := start(t, source)
+	s.diagnostics()
+	doc := TextDocumentIdentifier{URI: uri}
+
+	var symbols []DocumentSymbol
+	s.call("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: doc}, &symbols)
+	wantSymbols := []DocumentSymbol{
+		{Name: "add", Detail: "fn(a, b)", Kind: SymbolKindFunction, Range: Range{Position{0, 0}, Position{2, 1}}, SelectionRange: Range{Position{0, 4}, Position{0, 7}}},
+		{Name: "x", Kind: SymbolKindVariable, Range: Range{Position{3, 0}, Position{3, 17}}, SelectionRange: Range{Position{3, 4}, Position{3, 5}}},
+		{Name: "y", Detail: "fn(n)", Kind: SymbolKindFunction, Range: Range{Position{5, 0}, Position{7, 3}}, SelectionRange: Range{Position{5, 4}, Position{5, 5}}},
+	}
+	if !reflect.DeepEqual(symbols, wantSymbols) {
+		t.Errorf("documentSymbol:\ngot  %+v\nwant %+v", symbols, wantSymbols)
+	}
+
+	var folds []FoldingRange
+	s.call("textDocument/foldingRange", FoldingRangeParams{TextDocument: doc}, &folds)
+	wantFolds := []FoldingRange{{StartLine: 0, EndLine: 1}, {StartLine: 5, EndLine: 6}}
+	if !reflect.DeepEqual(folds, wantFolds) {
+		t.Errorf("foldingRange: got %+v, want %+v", folds, wantFolds)
+	}
+
+	var edits []TextEdit
+	s.call("textDocument/formatting", DocumentFormattingParams{TextDocument: doc, Options: FormattingOptions{TabSize: 4}}, &edits)
+	want := "let add = fn(a, b) {\n\ta + b\n};\nlet x = add(1, 2);\n// é\nlet y = fn(n) {\n\tif (n) {\n\t\ty(n)\n\t}\n};\n"
+	if len(edits) != 1 || edits[0].NewText != want || edits[0].Range != (Range{Position{0, 0}, Position{8, 0}}) {
+		t.Errorf("formatting: got %+v, want one edit of the whole document to %q", edits, want)
+	}
+
+	// A formatted document needs no edits
+	s.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: want}},
+	})
+	s.diagnostics()
+	s.call("textDocument/formatting", DocumentFormattingParams{TextDocument: doc, Options: FormattingOptions{TabSize: 4}}, &edits)
+	if len(edits) != 0 {
+		t.Errorf("formatting a formatted document: got %+v, want no edits", edits)
+	}
+	s.stop()
+}
+
+func TestUnknownMethod(t *testing.T) {
+	s := start(t, "")
+	s.diagnostics()
+	err := s.client.Call("textDocument/unknown", nil, nil)
+	if rerr, ok := err.(*ResponseError); !ok || rerr.Code != codeMethodNotFound {
+		t.Errorf("got %v, want a method not found error", err)
+	}
+	s.stop()
+}
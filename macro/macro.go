@@ -0,0 +1,269 @@
+// Package macro expands the macros of Monkey programs
+//
+// A macro is a macro literal bound by a let statement at the top level of the program:
+//
+//	let unless = macro(cond, cons, alt) { quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) }) };
+//
+// Define removes the definitions from the program, and Expand replaces every call of a macro with
+// the code its body quotes. The arguments of a call are not evaluated: an unquote in the quoted code
+// is replaced by its argument, in which the parameters stand for the argument expressions of the
+// call, and which is folded when it is constant. So unquote(cons) is the second argument of the call
+// and unquote(2 * 3) is 6. Expansion runs no code, so the body of a macro must be a single quote
+//
+// Expansion is hygienic for the names the quoted code declares: its lets and parameters are renamed
+// to names that the program does not use, so they cannot capture or hide the names of the arguments
+package macro
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/optimize"
+	"monkey/parser"
+	"monkey/resolver"
+	"sort"
+	"strings"
+)
+
+// The names of the calls that quote code and splice values into quoted code
+const (
+	Quote   = "quote"
+	Unquote = "unquote"
+)
+
+// MaxDepth is how deeply the expansion of a macro can contain calls of macros
+// Expansion evaluates no conditions, so a macro that calls itself never stops expanding
+const MaxDepth = 100
+
+// Env holds the macros of a program by name
+type Env map[string]*ast.MacroLiteral
+
+// Define removes the let statements at the top level of program whose value is a macro literal,
+// and returns the macros they define. A later definition of a name replaces an earlier one
+func Define(program *ast.Program) Env {
+	env := Env{}
+	stmts := program.Statements[:0]
+	for _, stmt := range program.Statements {
+		if ls, ok := stmt.(*ast.LetStatement); ok && ls.Name != nil {
+			if m, ok := ls.Value.(*ast.MacroLiteral); ok {
+				env[ls.Name.Value] = m
+				continue
+			}
+		}
+		stmts = append(stmts, stmt)
+	}
+	program.Statements = stmts
+	return env
+}
+
+// Expand replaces the calls of the macros of env in program with their expansions, in place
+// A call of a name that the program binds itself is not a macro call. The errors are a
+// parser.ErrorList of the calls that could not be expanded and of the macro literals that are
+// left in the program, which Define did not take
+func Expand(program *ast.Program, env Env) error {
+	e := &expander{env: env, info: resolver.Resolve(program), names: map[string]bool{}}
+	collect := func(n ast.Node) bool {
+		if id, ok := n.(*ast.Identifier); ok {
+			e.names[id.Value] = true
+		}
+		return n != nil
+	}
+	ast.Inspect(program, collect)
+	for _, m := range env {
+		ast.Inspect(m, collect)
+	}
+	e.expandAll(program, 1)
+	ast.Inspect(program, func(n ast.Node) bool {
+		if m, ok := n.(*ast.MacroLiteral); ok {
+			e.errs.Add(m.Pos(), "macro literal outside of a let statement at the top level")
+			return false
+		}
+		return n != nil
+	})
+	return e.errs.Err()
+}
+
+// expander holds the state of Expand
+type expander struct {
+	env   Env
+	info  *resolver.Info      // The bindings of the program before expansion
+	names map[string]bool     // Every name in use, to pick fresh names
+	site  *ast.CallExpression // The call in the program that is being expanded
+	deep  bool                // Whether the expansion of site went deeper than MaxDepth
+	errs  parser.ErrorList
+}
+
+// expandAll expands the macro calls of the tree rooted at node, bottom-up, so the arguments of
+// a call are expanded before the call. depth is the number of expansions node is the result of, plus one
+func (e *expander) expandAll(node ast.Node, depth int) ast.Node {
+	root, _ := ast.Rewrite(node, func(n ast.Node) ast.Node {
+		call, ok := n.(*ast.CallExpression)
+		if !ok {
+			return n
+		}
+		id, ok := call.Function.(*ast.Identifier)
+		if !ok || e.env[id.Value] == nil || e.info.Uses[id] != nil {
+			return n
+		}
+		return e.expand(call, e.env[id.Value], depth)
+	})
+	return root
+}
+
+// expand returns the expansion of a call of the macro m, or the call after reporting an error
+func (e *expander) expand(call *ast.CallExpression, m *ast.MacroLiteral, depth int) ast.Node {
+	name := call.Function.(*ast.Identifier).Value
+	if depth == 1 {
+		e.site, e.deep = call, false
+	}
+	if e.deep {
+		return call
+	}
+	if depth > MaxDepth {
+		// The error is reported once, at the call in the program
+		e.errs.Add(e.site.Pos(), fmt.Sprintf("expansion of macro %s is nested more than %d deep; is it recursive?", name, MaxDepth))
+		e.deep = true
+		return call
+	}
+	if len(call.Arguments) != len(m.Parameters) {
+		e.errs.Add(call.Pos(), fmt.Sprintf("macro %s takes %d arguments, got %d", name, len(m.Parameters), len(call.Arguments)))
+		return call
+	}
+	template := quoted(m)
+	if template == nil {
+		e.errs.Add(call.Pos(), fmt.Sprintf("the body of macro %s must be a single quote(expression)", name))
+		return call
+	}
+	args := map[string]ast.Expression{}
+	for i, pr := range m.Parameters {
+		args[pr.Value] = call.Arguments[i]
+	}
+	code := ast.Clone(template)
+	e.rename(code)
+	code, _ = ast.Rewrite(code, func(n ast.Node) ast.Node {
+		if expr := unquoted(n); expr != nil {
+			return substitute(optimize.Fold(expr), args)
+		}
+		return n
+	})
+	return e.expandAll(code, depth+1)
+}
+
+// quoted is a helper function that returns the expression quoted by the body of m, nil if the body
+// is not a single quote, possibly returned
+func quoted(m *ast.MacroLiteral) ast.Expression {
+	if m.Body == nil || len(m.Body.Statements) != 1 {
+		return nil
+	}
+	var expr ast.Expression
+	switch s := m.Body.Statements[0].(type) {
+	case *ast.ExpressionStatement:
+		expr = s.Expression
+	case *ast.ReturnStatement:
+		expr = s.ReturnValue
+	}
+	call, ok := expr.(*ast.CallExpression)
+	if !ok || len(call.Arguments) != 1 {
+		return nil
+	}
+	if id, ok := call.Function.(*ast.Identifier); !ok || id.Value != Quote {
+		return nil
+	}
+	return call.Arguments[0]
+}
+
+// unquoted is a helper function that returns the argument of n if n is a call of unquote
+func unquoted(n ast.Node) ast.Expression {
+	call, ok := n.(*ast.CallExpression)
+	if !ok || len(call.Arguments) != 1 {
+		return nil
+	}
+	if id, ok := call.Function.(*ast.Identifier); !ok || id.Value != Unquote {
+		return nil
+	}
+	return call.Arguments[0]
+}
+
+// substitute is a helper function that replaces the parameters in node with copies of their arguments
+func substitute(node ast.Node, args map[string]ast.Expression) ast.Node {
+	root, _ := ast.Rewrite(node, func(n ast.Node) ast.Node {
+		if id, ok := n.(*ast.Identifier); ok {
+			if arg, ok := args[id.Value]; ok {
+				return ast.Clone(arg)
+			}
+		}
+		return n
+	})
+	return root
+}
+
+// rename gives the names that code declares fresh names, outside of its unquote calls, which
+// belong to the macro rather than to the quoted code
+func (e *expander) rename(code ast.Node) {
+	expr, ok := code.(ast.Expression)
+	if !ok {
+		return
+	}
+	skip := map[*ast.Identifier]bool{}
+	ast.Inspect(code, func(n ast.Node) bool {
+		if arg := unquoted(n); arg != nil {
+			ast.Inspect(arg, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Identifier); ok {
+					skip[id] = true
+				}
+				return n != nil
+			})
+			return false
+		}
+		return n != nil
+	})
+	program := &ast.Program{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: expr}}}
+	info := resolver.Resolve(program)
+	// The symbols are renamed in the order of their declarations, so the fresh names do not
+	// depend on the order of a map
+	var symbols []*resolver.Symbol
+	renamed := map[*resolver.Symbol]bool{}
+	for _, sym := range info.Defs {
+		if !renamed[sym] && !skip[sym.Decl] {
+			renamed[sym] = true
+			symbols = append(symbols, sym)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Decl.Pos().Offset < symbols[j].Decl.Pos().Offset })
+	for _, sym := range symbols {
+		name := e.fresh(sym.Name)
+		for _, id := range append(append([]*ast.Identifier{}, sym.Decls...), sym.Uses...) {
+			if !skip[id] {
+				id.Value = name
+				id.Token.Literal = name
+			}
+		}
+	}
+}
+
+// fresh returns a name derived from name that is not in use, and marks it as used
+// Identifiers cannot contain digits, so the suffix counts with letters: x_a, x_b, ..., x_ba
+func (e *expander) fresh(name string) string {
+	for i := 0; ; i++ {
+		var suffix strings.Builder
+		for n := i; ; n = n/26 - 1 {
+			suffix.WriteByte(byte('a' + n%26))
+			if n < 26 {
+				break
+			}
+		}
+		candidate := name + "_" + reverse(suffix.String())
+		if !e.names[candidate] {
+			e.names[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// reverse is a helper function that reverses an ASCII string
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
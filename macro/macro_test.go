@@ -0,0 +1,170 @@
+package macro
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"monkey/ast"
+	"monkey/format"
+	"monkey/parser"
+	"monkey/resolver"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata with the current expansions")
+
+// expand expands the macros of src and returns the formatted result, followed by the errors
+func expand(t *testing.T, src string) string {
+	t.Helper()
+	program, err := parser.ParseFile("", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = Expand(program, Define(program))
+	out.WriteString(format.DefaultConfig.Edited(program, src))
+	if list, ok := err.(parser.ErrorList); ok {
+		out.WriteString("\nerrors:\n")
+		for _, e := range list {
+			out.WriteString(e.Error() + "\n")
+		}
+	}
+	return out.String()
+}
+
+// TestGolden expands every file of testdata and compares the result with the .golden file
+// next to it. Run the tests with -update to rewrite the golden files
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.mk"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no test files: %v", err)
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := expand(t, string(src))
+		golden := strings.TrimSuffix(file, ".mk") + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%s:\ngot\n%s\nwant\n%s", file, got, want)
+		}
+	}
+}
+
+func TestHygiene(t *testing.T) {
+	src := `let m = macro(x) { quote(fn(y) { let z = unquote(x); z + y }) };
+let y = 1;
+let z = 2;
+let z_a = 3;
+m(y + z + z_a);`
+	program, err := parser.ParseFile("", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Expand(program, Define(program)); err != nil {
+		t.Fatal(err)
+	}
+	fn := program.Statements[len(program.Statements)-1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if got := fn.Parameters[0].Value; got != "y_a" {
+		t.Errorf("the parameter y is renamed to %s, want y_a", got)
+	}
+	// z_a is in use, so the let of z gets the next name
+	if got := fn.Body.Statements[0].(*ast.LetStatement).Name.Value; got != "z_b" {
+		t.Errorf("the let of z is renamed to %s, want z_b", got)
+	}
+
+	// The names of the argument still refer to the lets of the program
+	info := resolver.Resolve(program)
+	ast.Inspect(fn, func(n ast.Node) bool {
+		id, ok := n.(*ast.Identifier)
+		if !ok {
+			return n != nil
+		}
+		sym := info.Uses[id]
+		if sym == nil {
+			sym = info.Defs[id]
+		}
+		switch {
+		case sym == nil:
+			t.Errorf("%s at %s is not bound", id.Value, id.Pos())
+		case id.Value == "y" || id.Value == "z" || id.Value == "z_a":
+			if sym.Scope != info.Scope {
+				t.Errorf("%s at %s is captured by the expansion", id.Value, id.Pos())
+			}
+		case sym.Scope == info.Scope:
+			t.Errorf("%s at %s refers to the program, not to the expansion", id.Value, id.Pos())
+		}
+		return true
+	})
+	if problems := info.Problems; len(problems) != 0 {
+		t.Errorf("the expansion has problems: %v", problems)
+	}
+}
+
+func TestRecursionLimit(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{
+			"let loop = macro(x) { quote(loop(unquote(x))) };\nloop(1);",
+			"2:1: expansion of macro loop is nested more than 100 deep; is it recursive?",
+		},
+		{
+			// Mutual recursion, and a macro that grows its argument
+			"let a = macro(x) { quote(b(unquote(x))) };\nlet b = macro(x) { quote(a(unquote(x) + 1)) };\n1 + a(0);",
+			"3:5: expansion of macro a is nested more than 100 deep; is it recursive?",
+		},
+		{
+			// A macro that calls itself twice would double the calls at every level, but the
+			// expansion stops at the first call that is too deep
+			"let tree = macro(x) { quote(tree(x) + tree(x)) };\ntree(1);",
+			"2:1: expansion of macro tree is nested more than 100 deep; is it recursive?",
+		},
+	}
+	for _, tt := range tests {
+		program, err := parser.ParseFile("", tt.src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Expand(program, Define(program))
+		list, ok := err.(parser.ErrorList)
+		if !ok || len(list) != 1 || list[0].Error() != tt.want {
+			t.Errorf("%q: got %v, want the single error %q", tt.src, err, tt.want)
+		}
+	}
+
+	// A chain of MaxDepth macros is deep but not too deep
+	var b strings.Builder
+	b.WriteString("let m_ = macro(x) { quote(unquote(x)) };\n")
+	name := "m_"
+	for i := 1; i < MaxDepth; i++ {
+		next := name + "a"
+		b.WriteString("let " + next + " = macro(x) { quote(" + name + "(unquote(x))) };\n")
+		name = next
+	}
+	b.WriteString(name + "(42);")
+	program, err := parser.ParseFile("", b.String(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Expand(program, Define(program)); err != nil {
+		t.Fatalf("a chain of %d macros: %v", MaxDepth, err)
+	}
+	if got := program.String(); got != "42;" {
+		t.Errorf("a chain of %d macros expands to %q, want 42", MaxDepth, got)
+	}
+}
@@ -0,0 +1,7 @@
+let two = macro(a, b) { quote(unquote(a) + unquote(b)) };
+two(1);
+let bad = macro(x) { x };
+bad(1);
+let loop = macro(x) { quote(loop(unquote(x))) };
+loop(1);
+let f = macro(x) { quote(x) }(1);
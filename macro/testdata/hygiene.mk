@@ -0,0 +1,12 @@
+// The names the quoted code declares are renamed, so they cannot capture the names of the arguments
+let twice = macro(x) {
+	quote(fn() { let value = unquote(x); let other = fn(value) { value }; other(value) + value })
+};
+let value = 1;
+let value_a = 2;
+twice(value + value_a);
+
+// Names the quoted code uses without declaring them keep referring to where the macro is expanded
+let scale = macro(x) { quote(unquote(x) * factor) };
+let factor = 3;
+scale(value);
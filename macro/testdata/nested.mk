@@ -0,0 +1,12 @@
+// Unquoted constants are folded before they are spliced in
+let square = macro(x) { quote(unquote(x) * unquote(x)) };
+let six = macro() { quote(unquote(2 * 3)) };
+square(six());
+
+// The expansion of a macro can call other macros, and arguments can be macro calls
+let inc = macro(x) { quote(unquote(x) + 1) };
+let incTwice = macro(x) { quote(inc(inc(unquote(x)))) };
+incTwice(square(2));
+
+// A name the program binds itself is not a macro call
+let f = fn(inc) { inc(1) };
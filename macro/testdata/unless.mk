@@ -0,0 +1,6 @@
+let unless = macro(cond, cons, alt) {
+	quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) })
+};
+
+// A macro picks which of its arguments run
+unless(10 > 5, puts(1), puts(2));
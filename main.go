@@ -2,24 +2,108 @@ package main
 
 import (
 	"fmt"
-	"monkey/lexer"
-	"monkey/parser"
+	"io"
+	"io/ioutil"
+	"monkey/repl"
+	"os"
+	"sort"
 )
 
-const PROMPT = ">> "
+// A subcommand of the monkey tool
+type command struct {
+	synopsis string                  // The arguments of the command
+	help     string                  // A one line description
+	run      func(args []string) int // Runs the command and returns the exit code
+}
+
+// The exit codes of the tool
+const (
+	exitOK      = 0 // Success
+	exitFailure = 1 // The input has errors or could not be read
+	exitUsage   = 2 // The command line is wrong
+)
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"repl":     {"repl", "start an interactive session", runRepl},
+		"tokens":   {"tokens [-json] <file|->", "print the token stream of a file", runTokens},
+		"parse":    {"parse [-format f] [-trace] <file|->", "print the syntax tree of a file", runParse},
+		"expand":   {"expand <file|->", "print a file with its macro definitions removed and its macro calls expanded", runExpand},
+		"fmt":      {"fmt [-d] [-w] <file|->...", "format files in the canonical format", runFmt},
+		"check":    {"check [-types] <file|->...", "report the parse and name errors of one or more files", runCheck},
+		"closures": {"closures <file|->", "print the variables every function literal captures", runClosures},
+		"lsp":      {"lsp", "run a language server over the standard input and output", runLSP},
+		"optimize": {"optimize [-stats] <file|->", "print a file with its constants folded and its dead code removed", runOptimize},
+		"vet":      {"vet [-enable r] [-disable r] [-list] <file|->...", "report suspicious code in one or more files", runVet},
+		"help":     {"help", "print this message", runHelp},
+	}
+}
 
 func main() {
-	input := `1 + 2 * 3 + 4`
-	lx := lexer.New(input)
-	ps := parser.New(lx)
+	if len(os.Args) < 2 {
+		os.Exit(runRepl(nil))
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "monkey: unknown command %q\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+	os.Exit(cmd.run(os.Args[2:]))
+}
 
-	program := ps.ParseProgram()
-	if program != nil {
-		for _, stmt := range program.Statements {
-			fmt.Printf("%v\n", stmt.String())
-		}
+// usage prints the list of subcommands to out
+func usage(out io.Writer) {
+	fmt.Fprintln(out, "usage: monkey <command> [arguments]")
+	fmt.Fprintln(out, "\nThe commands are:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "\tmonkey %-28s %s\n", commands[name].synopsis, commands[name].help)
 	}
+	fmt.Fprintln(out, "\nA file name of - reads from the standard input")
 }
 
-// Precedence
-// The precedence value of an operator defines which operator should be evaluated first
+// readSource reads the file at path, or the standard input if path is "-"
+func readSource(path string) (string, error) {
+	var src []byte
+	var err error
+	if path == "-" {
+		src, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		src, err = ioutil.ReadFile(path)
+	}
+	return string(src), err
+}
+
+// openSource opens the file at path for reading, or the standard input if path is "-"
+func openSource(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// displayName returns the name used for path in diagnostics
+func displayName(path string) string {
+	if path == "-" {
+		return "<stdin>"
+	}
+	return path
+}
+
+func runRepl(args []string) int {
+	fmt.Println("Monkey REPL. Input is parsed, not evaluated. Type :help for a list of commands")
+	repl.Start(os.Stdin, os.Stdout)
+	return exitOK
+}
+
+func runHelp(args []string) int {
+	usage(os.Stdout)
+	return exitOK
+}
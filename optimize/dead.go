@@ -0,0 +1,209 @@
+package optimize
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/resolver"
+)
+
+// Stats counts the statements a pass removed
+type Stats struct {
+	Unreachable int // Statements after a return
+	Branches    int // Statements of if branches that a constant condition never takes
+	Lets        int // Let statements of names that are never used
+}
+
+// Total returns the number of statements removed
+func (s Stats) Total() int {
+	return s.Unreachable + s.Branches + s.Lets
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("removed %s: %d unreachable, %d in untaken branches, %s", count(s.Total(), "statement"), s.Unreachable, s.Branches, count(s.Lets, "unused let"))
+}
+
+// count is a helper function that writes n things, in the plural unless n is 1
+func count(n int, thing string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, thing)
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+// Program eliminates the dead code of program and folds it until neither changes it any more,
+// since folding makes conditions constant and removing code can leave more to fold. It returns
+// how much was removed in all
+func Program(program *ast.Program) Stats {
+	stats := Eliminate(program)
+	for {
+		_, branches := foldAndCount(program)
+		s := Eliminate(program)
+		s.Branches += branches
+		if s.Total() == 0 {
+			return stats
+		}
+		stats.Unreachable += s.Unreachable
+		stats.Branches += s.Branches
+		stats.Lets += s.Lets
+	}
+}
+
+// Eliminate removes the code of program that never runs or whose result is never used, and
+// returns how much it removed. It is most useful with Fold, which makes the conditions constant
+//
+// The statements after a return are removed from their block, the branch of an if that its
+// constant condition never takes is emptied, and the let statements of a name that is never used are removed when their values are
+// pure. A value is pure when it calls no function, has no return and divides by nothing but
+// non-zero literals; as for Fold, operands of the wrong types are not considered. A let that is
+// the last statement of a block stays, since it makes the value of the block null
+func Eliminate(program *ast.Program) Stats {
+	var stats Stats
+	// The code is cut before its children are visited, so what is inside removed code is not counted
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Program:
+			n.Statements = stats.unreachable(n.Statements)
+		case *ast.BlockStatement:
+			n.Statements = stats.unreachable(n.Statements)
+		case *ast.IfExpression:
+			branch, ok := takenBranch(n)
+			if !ok {
+				break
+			}
+			if branch == n.Consequence && n.Alternative != nil {
+				stats.Branches += len(n.Alternative.Statements)
+				n.Alternative = nil
+			} else if branch != n.Consequence && n.Consequence != nil {
+				stats.Branches += len(n.Consequence.Statements)
+				n.Consequence.Statements = nil
+			}
+		}
+		return node != nil
+	})
+	// Removing a let may leave the names its value used unused in turn
+	for {
+		removed := unusedLets(program)
+		if len(removed) == 0 {
+			return stats
+		}
+		stats.Lets += len(removed)
+		ast.Rewrite(program, func(node ast.Node) ast.Node {
+			if ls, ok := node.(*ast.LetStatement); ok && removed[ls] {
+				return nil
+			}
+			return node
+		})
+	}
+}
+
+// unreachable cuts stmts after their first return statement and counts the statements it removes
+func (s *Stats) unreachable(stmts []ast.Statement) []ast.Statement {
+	for i, stmt := range stmts {
+		if _, ok := stmt.(*ast.ReturnStatement); ok {
+			s.Unreachable += len(stmts) - i - 1
+			return stmts[:i+1]
+		}
+	}
+	return stmts
+}
+
+// unusedLets returns the let statements of program that can be removed
+// The lets of a name go together: all of them are removed when none of them is impure or the last
+// statement of a block, and the name is only used in their own values
+func unusedLets(program *ast.Program) map[*ast.LetStatement]bool {
+	lets := map[*ast.Identifier]*ast.LetStatement{}
+	last := map[*ast.LetStatement]bool{}
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			if n.Name != nil {
+				lets[n.Name] = n
+			}
+		case *ast.BlockStatement:
+			if len(n.Statements) > 0 {
+				if ls, ok := n.Statements[len(n.Statements)-1].(*ast.LetStatement); ok {
+					last[ls] = true
+				}
+			}
+		}
+		return n != nil
+	})
+	info := resolver.Resolve(program)
+	removed := map[*ast.LetStatement]bool{}
+	for ident, sym := range info.Defs {
+		if sym.Kind != resolver.LetSymbol || ident != sym.Decl {
+			continue
+		}
+		var stmts []*ast.LetStatement
+		for _, decl := range sym.Decls {
+			ls := lets[decl]
+			if ls == nil || last[ls] || !pure(ls.Value, info) {
+				stmts = nil
+				break
+			}
+			stmts = append(stmts, ls)
+		}
+		if stmts != nil && !usedOutside(sym, stmts) {
+			for _, ls := range stmts {
+				removed[ls] = true
+			}
+		}
+	}
+	return removed
+}
+
+// usedOutside is a helper function that reports whether a use of sym is outside of stmts
+// The identifiers of stmts are looked up in the tree rather than by position, since folded
+// literals can be longer than the source they replace
+func usedOutside(sym *resolver.Symbol, stmts []*ast.LetStatement) bool {
+	inside := map[*ast.Identifier]bool{}
+	for _, ls := range stmts {
+		ast.Inspect(ls, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Identifier); ok {
+				inside[id] = true
+			}
+			return n != nil
+		})
+	}
+	for _, use := range sym.Uses {
+		if !inside[use] {
+			return true
+		}
+	}
+	return false
+}
+
+// pure is a helper function that reports whether evaluating expr has no effect and cannot fail
+// A return in an if of expr returns from the enclosing function, so expr is not pure then
+// The body of a function literal does not run when the function is created, so it is not looked at
+// An identifier that info does not resolve is an error when it is evaluated, so it is not pure
+func pure(expr ast.Expression, info *resolver.Info) bool {
+	if expr == nil {
+		return false
+	}
+	ok := true
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpression, *ast.ReturnStatement:
+			ok = false
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.Identifier:
+			if info.SymbolOf(n) == nil {
+				ok = false
+			}
+		case *ast.InfixExpression:
+			if n.Operator == "/" && (!isLiteral(n.Right) || isInteger(n.Right, 0)) {
+				ok = false
+			}
+		}
+		return ok && n != nil
+	})
+	return ok
+}
+
+// isLiteral is a helper function that reports whether expr is an integer literal
+func isLiteral(expr ast.Expression) bool {
+	_, ok := expr.(*ast.IntegerLiteral)
+	return ok
+}
@@ -0,0 +1,118 @@
+package optimize
+
+import (
+	"fmt"
+	"monkey/format"
+	"monkey/parser"
+	"monkey/printer"
+	"testing"
+)
+
+func ExampleProgram() {
+	src := `let f = fn(x) {
+	if (1 > 2) {
+		log(x);
+	}
+	return x * 1;
+	log(x);
+};
+let unused = 6 * 7;
+f(2);
+`
+	program, _ := parser.ParseFile("", src, 0)
+	stats := Program(program)
+	fmt.Println(stats)
+	// The removed let leaves no blank line
+	fmt.Print(format.DefaultConfig.Edited(program, src))
+	// Output:
+	// removed 3 statements: 1 unreachable, 1 in untaken branches, 1 unused let
+	// let f = fn(x) {
+	// 	return x;
+	// };
+	// f(2);
+}
+
+func ExampleEliminate() {
+	program, _ := parser.ParseFile("", "let a = 1; let b = a + 1; if (false) { f(); g() } else { h() }; return b; b;", 0)
+	// Without Fold, the if keeps its empty branch
+	fmt.Println(Eliminate(program))
+	fmt.Print(printer.String(program))
+	// Output:
+	// removed 3 statements: 1 unreachable, 2 in untaken branches, 0 unused lets
+	// let a = 1;
+	// let b = a + 1;
+	// if (false) {} else {
+	// 	h();
+	// };
+	// return b;
+}
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Stats
+	}{
+		{"let a = 1; a;", Stats{}},
+		// Untaken branches
+		{"if (false) { h(); }", Stats{Branches: 1}},
+		{"if (1 > 2) { h() } else { i() };", Stats{Branches: 1}},
+		{"if (true) { h() } else { i(); j() }; 1;", Stats{Branches: 2}},
+		{"let x = if (true) { 1 } else { h() } + 2; x;", Stats{Branches: 1}},
+		{"if (x) { h() } else { i() };", Stats{}},
+		// The code inside removed code is not counted again
+		{"if (false) { if (true) { h() } else { i() }; j() }", Stats{Branches: 2}},
+		{"fn() { return 1; if (false) { h() }; i() };", Stats{Unreachable: 2}},
+		{"if (1 > 2) { if (2 > 1) { h() } else { i() }; j() }", Stats{Branches: 2}},
+		// Unreachable code
+		{"let f = fn() { return 1; h(); i() }; f();", Stats{Unreachable: 2}},
+		{"return 1; h();", Stats{Unreachable: 1}},
+		// Unused lets, including those only the removed lets used
+		{"let a = 1; let b = a; 2;", Stats{Lets: 2}},
+		{"let f = fn() { f() }; 1;", Stats{Lets: 1}},
+		{"let a = 1; let a = a + 1; 2;", Stats{Lets: 2}},
+		// Lets that are used, impure or the last statement of a block stay
+		{"let a = h(); 1;", Stats{}},
+		{"let a = 1 / 0; 1;", Stats{}},
+		{"let a = 1 / x; 1;", Stats{}},
+		{"let q = undefinedName; 1;", Stats{}},
+		{"let q = if (true) { let b = 1; b + undefinedName }; 1;", Stats{}},
+		{"let x = true; let q = if (x) { let b = 1; b }; x;", Stats{Lets: 1}},
+		{"let a = if (x) { return 1; }; 1;", Stats{}},
+		{"let f = fn() { let a = 1; }; f();", Stats{}},
+		{"let a = 1; let f = fn() { a }; f();", Stats{}},
+		// A let used after a folded value is still used
+		{"let x = !1; x;", Stats{}},
+		// Removing code leaves more to fold and remove
+		{"let a = 1; let f = fn() { return 2; a }; f();", Stats{Unreachable: 1, Lets: 1}},
+		{"let a = 1; let y = if (false) { a } else { 2 } * 3; y;", Stats{Branches: 1, Lets: 1}},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.src)
+		if got := Program(program); got != tt.want {
+			t.Errorf("Program(%q) = %+v, want %+v", tt.src, got, tt.want)
+		}
+		if got := Program(program); got.Total() != 0 {
+			t.Errorf("Program(%q) removed %+v more the second time", tt.src, got)
+		}
+	}
+}
+
+func TestStatsString(t *testing.T) {
+	tests := []struct {
+		s    Stats
+		want string
+	}{
+		{Stats{Unreachable: 1, Branches: 2, Lets: 3}, "removed 6 statements: 1 unreachable, 2 in untaken branches, 3 unused lets"},
+		{Stats{Unreachable: 1}, "removed 1 statement: 1 unreachable, 0 in untaken branches, 0 unused lets"},
+		{Stats{Lets: 1}, "removed 1 statement: 0 unreachable, 0 in untaken branches, 1 unused let"},
+		{Stats{}, "removed 0 statements: 0 unreachable, 0 in untaken branches, 0 unused lets"},
+	}
+	for _, tt := range tests {
+		if got := tt.s.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+	if got := (Stats{Unreachable: 1, Branches: 2, Lets: 3}).Total(); got != 6 {
+		t.Errorf("Total() = %d, want 6", got)
+	}
+}
@@ -0,0 +1,298 @@
+// Package optimize rewrites Monkey programs into simpler programs that compute the same values
+//
+// Fold folds constant expressions, Eliminate removes dead code and Program runs both.
+// The rewrites assume these semantics: integers are 64 bit and wrap around, every value but false
+// and null is truthy, and an operator applied to operands of the wrong types is a runtime error.
+// A rewrite never removes a call, since a call may have side effects
+package optimize
+
+import (
+	"math"
+	"monkey/ast"
+	"monkey/token"
+	"strconv"
+)
+
+// Fold folds the constant expressions of the tree rooted at node and returns the new root
+// The tree is modified in place. An operation on integer and boolean literals is replaced by its
+// result, and x * 1, 1 * x, x / 1, x + 0, 0 + x and x - 0 by x. The identities assume that x is
+// an integer; for another value they drop the runtime error of the operation. A division by zero
+// is never folded, so that it still fails when the program runs
+//
+// An if with a constant condition is replaced by the branch it takes, and the branch it does not
+// take is dropped. The if stays, without its untaken branch, where its branch cannot replace it
+func Fold(node ast.Node) ast.Node {
+	root, _ := foldAndCount(node)
+	return root
+}
+
+// foldAndCount is Fold, and also returns the number of statements in the untaken branches it
+// dropped. As for Eliminate, the statements inside a dropped branch are not counted again
+func foldAndCount(node ast.Node) (ast.Node, int) {
+	f := &folder{
+		outer:   map[*ast.BlockStatement]*ast.BlockStatement{},
+		length:  map[*ast.BlockStatement]int{},
+		dropped: map[*ast.BlockStatement]bool{},
+	}
+	ast.Walk(blocks{f, nil}, node)
+	root, _ := ast.Rewrite(node, f.fold)
+	return root, f.count()
+}
+
+// folder holds the blocks of a tree as they were before it was folded, since folding moves
+// statements out of their blocks
+type folder struct {
+	outer   map[*ast.BlockStatement]*ast.BlockStatement // The block each block is in, nil at the top
+	length  map[*ast.BlockStatement]int                 // The number of statements of each block
+	dropped map[*ast.BlockStatement]bool                // The untaken branches that were dropped
+}
+
+// blocks is a Visitor that records the blocks of a tree in a folder
+type blocks struct {
+	f     *folder
+	block *ast.BlockStatement // The block the visited nodes are in
+}
+
+func (v blocks) Visit(node ast.Node) ast.Visitor {
+	if block, ok := node.(*ast.BlockStatement); ok {
+		v.f.outer[block] = v.block
+		v.f.length[block] = len(block.Statements)
+		return blocks{v.f, block}
+	}
+	if node == nil {
+		return nil
+	}
+	return v
+}
+
+// count returns the number of statements of the dropped branches that are not in another one
+func (f *folder) count() int {
+	n := 0
+	for block := range f.dropped {
+		inside := false
+		for outer := f.outer[block]; outer != nil && !inside; outer = f.outer[outer] {
+			inside = f.dropped[outer]
+		}
+		if !inside {
+			n += f.length[block]
+		}
+	}
+	return n
+}
+
+// fold is the callback of Fold; the children of node are already folded
+func (f *folder) fold(node ast.Node) ast.Node {
+	switch n := node.(type) {
+	case *ast.Program:
+		n.Statements = foldStatements(n.Statements)
+	case *ast.BlockStatement:
+		n.Statements = foldStatements(n.Statements)
+	case *ast.PrefixExpression:
+		if folded := foldPrefix(n); folded != nil {
+			return folded
+		}
+	case *ast.InfixExpression:
+		if folded := foldInfix(n); folded != nil {
+			return folded
+		}
+		return simplify(n)
+	case *ast.IfExpression:
+		branch, ok := takenBranch(n)
+		if !ok {
+			break
+		}
+		f.dropUntaken(n, branch)
+		// The branch can stand for the if when its value is the only thing it computes
+		if branch != nil && len(branch.Statements) == 1 {
+			if es, ok := branch.Statements[0].(*ast.ExpressionStatement); ok && es.Expression != nil {
+				return es.Expression
+			}
+		}
+	}
+	return node
+}
+
+// dropUntaken removes the branch of ie other than branch, which ie takes
+func (f *folder) dropUntaken(ie *ast.IfExpression, branch *ast.BlockStatement) {
+	if branch == ie.Consequence && ie.Alternative != nil {
+		f.dropped[ie.Alternative] = true
+		ie.Alternative = nil
+	} else if branch != ie.Consequence && ie.Consequence != nil && len(ie.Consequence.Statements) > 0 {
+		f.dropped[ie.Consequence] = true
+		ie.Consequence.Statements = nil
+	}
+}
+
+// foldPrefix returns the value of a prefix operator applied to a literal, or nil
+func foldPrefix(pe *ast.PrefixExpression) ast.Expression {
+	switch right := pe.Right.(type) {
+	case *ast.IntegerLiteral:
+		switch pe.Operator {
+		case "-":
+			return integer(pe, -right.Value)
+		case "!":
+			return boolean(pe, false)
+		}
+	case *ast.Boolean:
+		if pe.Operator == "!" {
+			return boolean(pe, !right.Value)
+		}
+	}
+	return nil
+}
+
+// foldInfix returns the value of an infix operator applied to two literals, or nil
+// Operands of different types are left alone, since applying the operator to them is an error
+func foldInfix(ie *ast.InfixExpression) ast.Expression {
+	switch left := ie.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := ie.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return nil
+		}
+		l, r := left.Value, right.Value
+		switch ie.Operator {
+		case "+":
+			return integer(ie, l+r)
+		case "-":
+			return integer(ie, l-r)
+		case "*":
+			return integer(ie, l*r)
+		case "/":
+			if r == 0 {
+				return nil
+			}
+			return integer(ie, l/r)
+		case "<":
+			return boolean(ie, l < r)
+		case ">":
+			return boolean(ie, l > r)
+		case "==":
+			return boolean(ie, l == r)
+		case "!=":
+			return boolean(ie, l != r)
+		}
+	case *ast.Boolean:
+		right, ok := ie.Right.(*ast.Boolean)
+		if !ok {
+			return nil
+		}
+		switch ie.Operator {
+		case "==":
+			return boolean(ie, left.Value == right.Value)
+		case "!=":
+			return boolean(ie, left.Value != right.Value)
+		}
+	}
+	return nil
+}
+
+// simplify applies the identities of addition, subtraction, multiplication and division to ie
+func simplify(ie *ast.InfixExpression) ast.Expression {
+	switch ie.Operator {
+	case "+":
+		if isInteger(ie.Right, 0) {
+			return ie.Left
+		}
+		if isInteger(ie.Left, 0) {
+			return ie.Right
+		}
+	case "-":
+		if isInteger(ie.Right, 0) {
+			return ie.Left
+		}
+	case "*":
+		if isInteger(ie.Right, 1) {
+			return ie.Left
+		}
+		if isInteger(ie.Left, 1) {
+			return ie.Right
+		}
+	case "/":
+		if isInteger(ie.Right, 1) {
+			return ie.Left
+		}
+	}
+	return ie
+}
+
+// foldStatements splices the branch that an if statement with a constant condition takes into
+// stmts; the branch it does not take is already dropped. A branch that declares a name keeps its block, so that the name stays local to it. An if that
+// takes no branch is dropped, unless it is the last statement and its null is the value of the list
+func foldStatements(stmts []ast.Statement) []ast.Statement {
+	var out []ast.Statement
+	for i, stmt := range stmts {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			out = append(out, stmt)
+			continue
+		}
+		ie, ok := es.Expression.(*ast.IfExpression)
+		if !ok {
+			out = append(out, stmt)
+			continue
+		}
+		branch, ok := takenBranch(ie)
+		switch {
+		case !ok || branch != nil && declares(branch):
+			out = append(out, stmt)
+		case branch == nil || len(branch.Statements) == 0:
+			if i == len(stmts)-1 {
+				out = append(out, stmt)
+			}
+		default:
+			out = append(out, branch.Statements...)
+		}
+	}
+	return out
+}
+
+// takenBranch returns the branch an if with a constant condition takes, nil if it has no else
+// and takes it. The result is false if the condition is not constant
+func takenBranch(ie *ast.IfExpression) (*ast.BlockStatement, bool) {
+	switch cond := ie.Condition.(type) {
+	case *ast.IntegerLiteral:
+		return ie.Consequence, true
+	case *ast.Boolean:
+		if cond.Value {
+			return ie.Consequence, true
+		}
+		return ie.Alternative, true
+	}
+	return nil, false
+}
+
+// declares is a helper function that reports whether a let statement is directly in block
+func declares(block *ast.BlockStatement) bool {
+	for _, stmt := range block.Statements {
+		if _, ok := stmt.(*ast.LetStatement); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isInteger is a helper function that reports whether expr is the integer literal value
+func isInteger(expr ast.Expression, value int64) bool {
+	lit, ok := expr.(*ast.IntegerLiteral)
+	return ok && lit.Value == value
+}
+
+// integer returns an integer literal with the value of expr, or nil for the smallest integer,
+// which has no literal
+func integer(expr ast.Expression, value int64) ast.Expression {
+	if value == math.MinInt64 {
+		return nil
+	}
+	tok := token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10), Pos: expr.Pos()}
+	return &ast.IntegerLiteral{Token: tok, Value: value}
+}
+
+// boolean returns a boolean literal with the value of expr
+func boolean(expr ast.Expression, value bool) ast.Expression {
+	tok := token.Token{Type: token.FALSE, Literal: "false", Pos: expr.Pos()}
+	if value {
+		tok = token.Token{Type: token.TRUE, Literal: "true", Pos: expr.Pos()}
+	}
+	return &ast.Boolean{Token: tok, Value: value}
+}
@@ -0,0 +1,103 @@
+package optimize
+
+import (
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/printer"
+	"testing"
+)
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	program, err := parser.ParseFile("", src, 0)
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	return program
+}
+
+// foldTests are inputs of Fold and the programs they fold to, which are compared once printed
+// So the expected programs can be written on one line
+var foldTests = []struct {
+	in, want string
+}{
+	// Operators on literals
+	{"1 + 2 * 3;", "7;"},
+	{"let y = (10 - 4) / 3;", "let y = 2;"},
+	{"7 / -2;", "-3;"},
+	{"-(2 - 5);", "3;"},
+	{"1 < 2; 1 > 2; 3 == 3; 3 != 3;", "true; false; true; false;"},
+	{"!true; !!false; !5;", "false; false; false;"},
+	{"true == false; true != false;", "false; true;"},
+	// Integers wrap around
+	{"9223372036854775807 * 2;", "-2;"},
+	{"-9223372036854775807 - 2;", "9223372036854775807;"},
+	// The smallest integer has no literal, so the expressions that compute it stay
+	{"9223372036854775807 + 1;", "9223372036854775807 + 1;"},
+	{"-9223372036854775807 - 1;", "-9223372036854775807 - 1;"},
+	// A division by zero is never folded, whatever the dividend
+	{"1 / 0;", "1 / 0;"},
+	{"x / 0;", "x / 0;"},
+	{"x / (2 - 2);", "x / 0;"},
+	{"0 / 0 + 1;", "0 / 0 + 1;"},
+	// Operands of different types are an error when the program runs
+	{"1 + true;", "1 + true;"},
+	{"1 == true;", "1 == true;"},
+	{"-true;", "-true;"},
+	{"true < false;", "true < false;"},
+	// Identities
+	{"x + 0; 0 + x; x - 0; x * 1; 1 * x; x / 1;", "x; x; x; x; x; x;"},
+	{"0 - x; x * 0; 1 / x;", "0 - x; x * 0; 1 / x;"},
+	{"f(x) * (3 - 2);", "f(x);"},
+	{"let y = (x + 0) * (1 * x);", "let y = x * x;"},
+	// An if with a constant condition is replaced by the branch it takes
+	{"if (true) { a } else { b };", "a;"},
+	{"if (true) { 1 } else { };", "1;"},
+	{"let y = if (1 < 2) { 5 };", "let y = 5;"},
+	{"let y = if (0) { x };", "let y = x;"},
+	{"let y = if (1 > 2) { f() } else { 3 };", "let y = 3;"},
+	{"if (true) { f(); g() } else { h() };", "f(); g();"},
+	{"if (false) { f() } else { g(); h() }; 1;", "g(); h(); 1;"},
+	{"if (false) { f() }; 1;", "1;"},
+	// The last if of a list is its value, which is null when it takes no branch
+	{"if (false) { f() };", "if (false) { };"},
+	// A branch that declares a name keeps its block
+	{"if (true) { let y = 1; y } else { f() };", "if (true) { let y = 1; y };"},
+	// An if that its branch cannot stand for only loses the branch it does not take
+	{"let y = if (false) { f() } else { g(); 1 };", "let y = if (false) { } else { g(); 1 };"},
+	{"if (x) { 1 + 1 } else { 2 * 2 };", "if (x) { 2 } else { 4 };"},
+	// Folding reaches into function literals and call arguments
+	{"let f = fn(a) { a * (2 + 2) }; f(3 - 1);", "let f = fn(a) { a * 4 }; f(2);"},
+}
+
+func TestFold(t *testing.T) {
+	for _, tt := range foldTests {
+		program := parse(t, tt.in)
+		Fold(program)
+		got := printer.String(program)
+		want := printer.String(parse(t, tt.want))
+		if got != want {
+			t.Errorf("Fold(%q):\ngot\n%s\nwant\n%s", tt.in, got, want)
+		}
+
+		// Folding again changes nothing
+		again := printer.String(Fold(parse(t, got)))
+		if again != got {
+			t.Errorf("Fold(%q) is not idempotent:\n%s\nthen\n%s", tt.in, got, again)
+		}
+	}
+}
+
+func TestFoldExpression(t *testing.T) {
+	expr, err := parser.ParseExpr("2 * (x + 0) - (3 - 3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Fold(expr)
+	if s := printer.String(got); s != "2 * x" {
+		t.Errorf("Fold of an expression = %q, want %q", s, "2 * x")
+	}
+	if pos := got.Pos(); pos != expr.Pos() {
+		t.Errorf("the folded expression starts at %s, want %s", pos, expr.Pos())
+	}
+}
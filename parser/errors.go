@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+// Error is a parser error with the position it was found at
+type Error struct {
+	Filename string         // The name of the file, may be empty
+	Pos      token.Position // The position of the offending token
+	Msg      string
+}
+
+// Error formats the error as "file:line:column: message"
+// The file name is left out when it is empty
+func (e *Error) Error() string {
+	if e.Filename != "" {
+		return fmt.Sprintf("%s:%s: %s", e.Filename, e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorList is a list of parser errors in the order they were found
+type ErrorList []*Error
+
+// Add appends an error at pos to the list
+func (l *ErrorList) Add(pos token.Position, msg string) {
+	*l = append(*l, &Error{Pos: pos, Msg: msg})
+}
+
+// Error formats the first error and the number of the others
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	case 2:
+		return fmt.Sprintf("%s (and 1 more error)", l[0])
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns the list as an error, or nil if the list is empty
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,249 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/token"
+	"sort"
+	"strings"
+)
+
+// Edit replaces the bytes from Start up to End of a source with Text
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Document is a source together with its tree, for a source that is edited often, such as in an editor
+// Apply reparses only the part of the source an edit can change. The rest of the tree is reused,
+// and the tree, the comments and the errors are the same as those of a full parse
+//
+// The statements that follow an edit are reused with their positions moved. A Program returned
+// before an edit shares them, so its positions are only valid until the next call to Apply
+type Document struct {
+	src     string
+	program *ast.Program
+	steps   []step
+	eof     token.Token
+}
+
+// step is what one round of the loop of ParseProgram parsed
+// The parser is in the same state at the start of every round, with the first token of the
+// step as its current token. So a step only depends on the source from its first token up to
+// the first token of the next step, which the parser has looked at as its peek token
+type step struct {
+	first  token.Token   // The first token of the step
+	stmt   ast.Statement // The statement parsed, nil if it was dropped
+	errors ErrorList     // The errors found in the step
+}
+
+// NewDocument parses src into a Document
+func NewDocument(src string) *Document {
+	d := &Document{}
+	d.src = src
+	steps, eof, comments, _ := parseSteps(src, token.Position{Offset: 0, Line: 1, Column: 1}, nil)
+	d.steps = steps
+	d.eof = eof
+	d.program = d.build(comments)
+	return d
+}
+
+// Source returns the current source
+func (d *Document) Source() string {
+	return d.src
+}
+
+// Program returns the tree of the current source, with its comments
+func (d *Document) Program() *ast.Program {
+	return d.program
+}
+
+// Err returns the errors of the current source as an ErrorList, or nil
+func (d *Document) Err() error {
+	var list ErrorList
+	for _, s := range d.steps {
+		list = append(list, s.errors...)
+	}
+	return list.Err()
+}
+
+// Apply applies the edit to the source and updates the tree
+func (d *Document) Apply(e Edit) error {
+	if e.Start < 0 || e.Start > e.End || e.End > len(d.src) {
+		return fmt.Errorf("parser: edit [%d,%d) out of range [0,%d)", e.Start, e.End, len(d.src))
+	}
+	src := d.src[:e.Start] + e.Text + d.src[e.End:]
+
+	// The steps before the edit are kept if the first token of the step after them ends before it
+	keep := 0
+	for keep < len(d.steps) && d.next(keep).End().Offset < e.Start {
+		keep++
+	}
+	if keep == len(d.steps) && keep > 0 {
+		// The source ended before the edit, at a NUL byte. The last step is parsed again, since
+		// what follows its statement may no longer be the end of the source
+		keep--
+	}
+	start := token.Position{Offset: 0, Line: 1, Column: 1}
+	if keep > 0 {
+		start = d.steps[keep].first.Pos
+	}
+
+	// The steps after the edit can be reused from the first one that starts on a later line.
+	// From there on the source is the same and only the offsets and the lines move
+	delta := len(e.Text) - (e.End - e.Start)
+	lines := strings.Count(e.Text, "\n") - strings.Count(d.src[e.Start:e.End], "\n")
+	afterLine := strings.IndexByte(d.src[e.End:], '\n')
+	resync := func(pos token.Position) int {
+		old := pos.Offset - delta
+		if afterLine < 0 || old <= e.End+afterLine {
+			return -1
+		}
+		i := sort.Search(len(d.steps), func(i int) bool { return d.steps[i].first.Pos.Offset >= old })
+		if i < len(d.steps) && d.steps[i].first.Pos.Offset == old {
+			return i
+		}
+		return -1
+	}
+	parsed, eof, comments, reuse := parseSteps(src, start, resync)
+
+	steps := append(d.steps[:keep:keep], parsed...)
+	oldComments := d.program.Comments
+	kept := sort.Search(len(oldComments), func(i int) bool { return oldComments[i].Pos.Offset >= start.Offset })
+	comments = append(oldComments[:kept:kept], comments...)
+	if reuse >= 0 {
+		from := d.steps[reuse].first.Pos.Offset
+		for _, s := range d.steps[reuse:] {
+			s.first.Pos = shift(s.first.Pos, delta, lines)
+			if s.stmt != nil {
+				shiftNode(s.stmt, delta, lines)
+			}
+			for _, err := range s.errors {
+				err.Pos = shift(err.Pos, delta, lines)
+			}
+			steps = append(steps, s)
+		}
+		for _, c := range oldComments[sort.Search(len(oldComments), func(i int) bool { return oldComments[i].Pos.Offset >= from }):] {
+			c.Pos = shift(c.Pos, delta, lines)
+			comments = append(comments, c)
+		}
+		eof = d.eof
+		eof.Pos = shift(eof.Pos, delta, lines)
+	}
+	d.src = src
+	d.steps = steps
+	d.eof = eof
+	d.program = d.build(comments)
+	return nil
+}
+
+// next returns the first token of the step after step i, the EOF token after the last step
+func (d *Document) next(i int) token.Token {
+	if i+1 < len(d.steps) {
+		return d.steps[i+1].first
+	}
+	return d.eof
+}
+
+// build is a helper function that makes the Program of the steps
+func (d *Document) build(comments []token.Token) *ast.Program {
+	if len(comments) == 0 {
+		// As in a full parse
+		comments = nil
+	}
+	program := &ast.Program{Statements: []ast.Statement{}, Comments: comments}
+	for _, s := range d.steps {
+		if s.stmt != nil {
+			program.Statements = append(program.Statements, s.stmt)
+		}
+	}
+	return program
+}
+
+// parseSteps parses src from start, in the steps of ParseProgram
+// After every step it calls resync, if not nil, with the position of the next step. If resync
+// returns an index other than -1, parsing stops there and the index is returned as reuse
+// The comments are those from start up to where parsing stopped
+func parseSteps(src string, start token.Position, resync func(token.Position) int) (steps []step, eof token.Token, comments []token.Token, reuse int) {
+	p := New(lexer.NewAt(src, start))
+	p.mode = ParseComments | AllErrors
+	reuse = -1
+	for p.currentToken.Type != token.EOF {
+		s := step{first: p.currentToken}
+		errs := len(p.errorList)
+		s.stmt = p.parseStatement()
+		p.nextToken()
+		s.errors = append(ErrorList(nil), p.errorList[errs:]...)
+		steps = append(steps, s)
+		if resync != nil && p.currentToken.Type != token.EOF {
+			if reuse = resync(p.currentToken.Pos); reuse >= 0 {
+				break
+			}
+		}
+	}
+	comments = p.l.Comments()
+	if reuse >= 0 {
+		// Leave the comments after the resync point to the reused steps
+		end := p.currentToken.Pos.Offset
+		i := sort.Search(len(comments), func(i int) bool { return comments[i].Pos.Offset >= end })
+		comments = comments[:i]
+	}
+	return steps, p.currentToken, comments, reuse
+}
+
+// shift is a helper function that moves pos by delta bytes and lines lines
+func shift(pos token.Position, delta, lines int) token.Position {
+	if pos.IsValid() {
+		pos.Offset += delta
+		pos.Line += lines
+	}
+	return pos
+}
+
+// shiftNode is a helper function that moves the positions of the tree rooted at node
+func shiftNode(node ast.Node, delta, lines int) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.ReturnStatement:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.ExpressionStatement:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.BlockStatement:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+			n.Rbrace = shift(n.Rbrace, delta, lines)
+		case *ast.Identifier:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.IntegerLiteral:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.Boolean:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.PrefixExpression:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.InfixExpression:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.IfExpression:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.FunctionLiteral:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.MacroLiteral:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+		case *ast.CallExpression:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+			n.Rparen = shift(n.Rparen, delta, lines)
+		case *ast.NamedType:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+			n.Rbracket = shift(n.Rbracket, delta, lines)
+		case *ast.ListType:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+			n.Rbracket = shift(n.Rbracket, delta, lines)
+		case *ast.FunctionType:
+			n.Token.Pos = shift(n.Token.Pos, delta, lines)
+			n.Rparen = shift(n.Rparen, delta, lines)
+		}
+		return true
+	})
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"math/rand"
+	"monkey/ast"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// script is a sample of the statements, blocks and comments an edited source is made of
+const script = `// The adder makes functions that add x
+let adder = fn(x) { fn(y) { x + y } };
+let addTwo = adder(2);
+
+let max = fn(a, b) {
+	if (a > b) {
+		return a;
+	}
+	b
+};
+// Negatives and grouping
+let n = -(max(addTwo(3), 7) * 2) / 3;
+if (n == 0) { n } else { !true }
+`
+
+// pieces are the texts that random edits insert
+var pieces = []string{"let", " ", "\n", "x", "y", "==", "=", "!", "// c\n", "/", "12", "(", ")", "{", "}", ";", ",", "<", "fn", "if", "else", "return", "-", "+", "\n\n", "\x00"}
+
+// checkDocument fails the test if the tree, the comments or the errors of d differ from those
+// of a full parse of its source
+func checkDocument(t *testing.T, d *Document, edits []Edit) {
+	t.Helper()
+	program, err := ParseFile("", d.Source(), ParseComments|AllErrors)
+	if !ast.Equal(d.Program(), program, 0) {
+		t.Fatalf("after edits %q:\nincremental %q\nfull        %q", edits, d.Program().String(), program.String())
+	}
+	if !reflect.DeepEqual(d.Program().Comments, program.Comments) {
+		t.Fatalf("after edits %q:\nincremental comments %v\nfull comments        %v", edits, d.Program().Comments, program.Comments)
+	}
+	if !reflect.DeepEqual(d.Err(), err) {
+		t.Fatalf("after edits %q:\nincremental errors %v\nfull errors        %v", edits, d.Err(), err)
+	}
+}
+
+func TestApplyRandomEdits(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		src := script
+		d := NewDocument(src)
+		var edits []Edit
+		for k := 0; k < 20; k++ {
+			start := r.Intn(len(src) + 1)
+			end := start + r.Intn(4)
+			if end > len(src) {
+				end = len(src)
+			}
+			var text strings.Builder
+			for j := r.Intn(3); j > 0; j-- {
+				text.WriteString(pieces[r.Intn(len(pieces))])
+			}
+			e := Edit{Start: start, End: end, Text: text.String()}
+			edits = append(edits, e)
+			if err := d.Apply(e); err != nil {
+				t.Fatal(err)
+			}
+			src = src[:start] + e.Text + src[end:]
+			if d.Source() != src {
+				t.Fatalf("after edits %q: source %q, want %q", edits, d.Source(), src)
+			}
+			checkDocument(t, d, edits)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		src   string
+		edits []Edit
+	}{
+		{"let a = 1;", []Edit{{10, 10, " let b = a;"}}},
+		{"let a = 1;\nlet b = 2;", []Edit{{8, 9, "fn(x) { x }"}}},
+		{"let a = 1;\nlet b = 2;", []Edit{{10, 10, "\n// c"}, {0, 0, "!"}}},
+		{"let a = fn(x) {\n\tx\n};\nlet b = 2;", []Edit{{14, 15, ""}, {14, 14, "{"}}},
+		{"let a = 1;\n\nlet b = 2;", []Edit{{10, 12, ""}, {0, 20, ""}}},
+		// A NUL byte ends the source, so the edit is after the last token
+		{"let a = 1;\x00 b", []Edit{{13, 13, "c"}}},
+		{"let a = 1;\x00 b", []Edit{{10, 11, ""}}},
+		{"\x00 b", []Edit{{3, 3, "c"}}},
+	}
+	for _, tt := range tests {
+		d := NewDocument(tt.src)
+		for i, e := range tt.edits {
+			if err := d.Apply(e); err != nil {
+				t.Fatalf("%q: %s", tt.src, err)
+			}
+			checkDocument(t, d, tt.edits[:i+1])
+		}
+	}
+}
+
+func TestApplyOutOfRange(t *testing.T) {
+	d := NewDocument("let a = 1;")
+	for _, e := range []Edit{{-1, 0, ""}, {3, 2, ""}, {0, 11, ""}} {
+		if err := d.Apply(e); err == nil {
+			t.Errorf("Apply(%v) = nil, want an error", e)
+		}
+	}
+	if d.Source() != "let a = 1;" {
+		t.Errorf("source changed to %q", d.Source())
+	}
+}
+
+// large is a script of about 100KB
+var large = strings.Repeat(script, 250)
+
+func BenchmarkParseFile(b *testing.B) {
+	b.SetBytes(int64(len(large)))
+	for i := 0; i < b.N; i++ {
+		ParseFile("", large, ParseComments|AllErrors)
+	}
+}
+
+func BenchmarkApply(b *testing.B) {
+	d := NewDocument(large)
+	middle := len(large) / 2
+	b.SetBytes(int64(len(large)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Typing and deleting a character keeps the source the same size
+		if i%2 == 0 {
+			d.Apply(Edit{Start: middle, End: middle, Text: "x"})
+		} else {
+			d.Apply(Edit{Start: middle, End: middle + 1, Text: ""})
+		}
+	}
+}
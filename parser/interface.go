@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/token"
+	"os"
+)
+
+// Mode controls ParseFile and ParseExpr. The bits can be combined
+type Mode uint
+
+const (
+	ParseComments    Mode = 1 << iota // Keep the comments in Program.Comments
+	Trace                             // Write a trace of the parsing functions to the standard error
+	StopAtFirstError                  // Stop parsing after the statement with the first error
+	AllErrors                         // Report all errors, not only the first 10
+)
+
+// The number of errors reported without AllErrors
+const maxErrors = 10
+
+// ParseFile parses the source of a file and returns its tree
+// src may be a string, a []byte or an io.Reader. If src is nil the file named filename is read.
+// filename is also used in the error messages. If there are errors the tree is returned as far
+// as it could be parsed, together with an ErrorList
+func ParseFile(filename string, src interface{}, mode Mode) (*ast.Program, error) {
+	text, err := readSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	p := newParser(text, mode)
+	program := p.ParseProgram()
+	if mode&ParseComments == 0 {
+		program.Comments = nil
+	}
+	return program, p.err(filename)
+}
+
+// ParseExpr parses src as a single expression, optionally followed by a semicolon
+// If there are errors the expression is nil
+func ParseExpr(src string) (ast.Expression, error) {
+	p := newParser(src, 0)
+	expr := p.parseExpression(LOWEST)
+	if len(p.errors) == 0 {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		if !p.peekTokenIs(token.EOF) {
+			p.errorf(p.peekToken.Pos, "Expected end of expression. Got %s instead", p.peekToken.Type)
+		}
+	}
+	if err := p.err(""); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// newParser is a helper function to create a Parser for src in the given mode
+func newParser(src string, mode Mode) *Parser {
+	p := New(lexer.New(src))
+	p.mode = mode
+	if mode&Trace != 0 {
+		p.SetTrace(os.Stderr)
+	}
+	return p
+}
+
+// err is a helper function that returns the errors of the parser as an ErrorList, or nil
+func (p *Parser) err(filename string) error {
+	list := p.errorList
+	if p.mode&AllErrors == 0 && len(list) > maxErrors {
+		list = list[:maxErrors]
+	}
+	for _, e := range list {
+		e.Filename = filename
+	}
+	return list.Err()
+}
+
+// readSource is a helper function that returns the source given to ParseFile
+func readSource(filename string, src interface{}) (string, error) {
+	switch s := src.(type) {
+	case nil:
+		data, err := ioutil.ReadFile(filename)
+		return string(data), err
+	case string:
+		return s, nil
+	case []byte:
+		return string(s), nil
+	case *bytes.Buffer:
+		return s.String(), nil
+	case io.Reader:
+		data, err := ioutil.ReadAll(s)
+		return string(data), err
+	}
+	return "", errors.New("parser: invalid source")
+}
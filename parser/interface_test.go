@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"io/ioutil"
+	"monkey/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFileSources(t *testing.T) {
+	const src = "let x = 1; // one\nx + 2;"
+	dir, err := ioutil.TempDir("", "parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.mk")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []interface{}{src, []byte(src), strings.NewReader(src), nil} {
+		program, err := ParseFile(path, s, 0)
+		if err != nil {
+			t.Errorf("%T: %v", s, err)
+			continue
+		}
+		if got := program.String(); got != "let x = 1;(x + 2);" {
+			t.Errorf("%T: parsed to %q", s, got)
+		}
+	}
+	if _, err := ParseFile("", 42, 0); err == nil || err.Error() != "parser: invalid source" {
+		t.Errorf("an int source: got %v, want an error", err)
+	}
+	if _, err := ParseFile(filepath.Join(dir, "missing.mk"), nil, 0); !os.IsNotExist(err) {
+		t.Errorf("a missing file: got %v, want a not exist error", err)
+	}
+}
+
+func TestParseFileModes(t *testing.T) {
+	program, _ := ParseFile("", "// a\nlet x = 1; // b", 0)
+	if program.Comments != nil {
+		t.Errorf("the comments are kept without ParseComments: %v", program.Comments)
+	}
+	program, _ = ParseFile("", "// a\nlet x = 1; // b", ParseComments)
+	if len(program.Comments) != 2 || program.Comments[1].Literal != "// b" {
+		t.Errorf("the comments with ParseComments are %v", program.Comments)
+	}
+
+	// Every line has an error
+	src := strings.Repeat("let x 1;\n", 12)
+	tests := []struct {
+		mode   Mode
+		errors int
+	}{
+		{0, 10},
+		{AllErrors, 12},
+		{StopAtFirstError, 1},
+		{StopAtFirstError | AllErrors, 1},
+	}
+	for _, tt := range tests {
+		_, err := ParseFile("rules.mk", src, tt.mode)
+		list, ok := err.(ErrorList)
+		if !ok || len(list) != tt.errors {
+			t.Errorf("mode %b: got %v, want %d errors", tt.mode, err, tt.errors)
+			continue
+		}
+		if got, want := list[0].Error(), "rules.mk:1:7: Expected next token to be =. Got INT instead"; got != want {
+			t.Errorf("mode %b: the first error is %q, want %q", tt.mode, got, want)
+		}
+	}
+
+	// The statements before the first error are parsed
+	program, err := ParseFile("", "let a = 1; let b = ); let c = 3; let d = );", StopAtFirstError)
+	if err == nil || len(program.Statements) != 2 || program.Statements[0].String() != "let a = 1;" {
+		t.Errorf("StopAtFirstError parsed %q, %v", program.String(), err)
+	}
+}
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		src, want, err string
+	}{
+		{"1 + 2 * x", "(1 + (2 * x))", ""},
+		{"f(a)(b);", "f(a)(b)", ""},
+		{"fn(x) { x }", "fn(x) { x; }", ""},
+		{"1 + 2; 3", "", "1:8: Expected end of expression. Got INT instead"},
+		{"1 +", "", "1:4: No prefix parsing function for EOF found"},
+		{"", "", "1:1: No prefix parsing function for EOF found"},
+		{"let x = 1;", "", "1:1: No prefix parsing function for LET found"},
+	}
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.src)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err || expr != nil {
+				t.Errorf("ParseExpr(%q) = %v, %v; want the error %q", tt.src, expr, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || expr.String() != tt.want {
+			t.Errorf("ParseExpr(%q) = %v, %v; want %s", tt.src, expr, err, tt.want)
+		}
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	var list ErrorList
+	if list.Err() != nil || list.Error() != "no errors" {
+		t.Errorf("an empty list: %v, %q", list.Err(), list.Error())
+	}
+	list.Add(token.Position{Line: 1, Column: 2}, "first")
+	if got := list.Err().Error(); got != "1:2: first" {
+		t.Errorf("one error: %q", got)
+	}
+	list.Add(token.Position{Line: 3, Column: 4}, "second")
+	if got := list.Error(); got != "1:2: first (and 1 more error)" {
+		t.Errorf("two errors: %q", got)
+	}
+	list.Add(token.Position{Line: 5, Column: 6}, "third")
+	list[0].Filename = "a.mk"
+	if got := list.Error(); got != "a.mk:1:2: first (and 2 more errors)" {
+		t.Errorf("three errors: %q", got)
+	}
+}
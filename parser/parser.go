@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"io"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
@@ -13,8 +14,12 @@ type Parser struct {
 	currentToken           token.Token
 	peekToken              token.Token
 	errors                 []string
+	errorList              ErrorList // The errors with their positions
+	mode                   Mode      // Set by ParseFile and ParseExpr
 	prefixParsingFunctions map[token.TokenType]prefixParsingFunction
 	infixParsingFunctions  map[token.TokenType]infixParsingFunction
+	tracer                 io.Writer // Where the trace is written, nil when tracing is off
+	traceDepth             int       // The nesting depth of the trace
 }
 
 // New is a helper function to create a new Parser
@@ -36,6 +41,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
 	// Register the infix-parsing-function which is the same for all infix operators
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
@@ -45,6 +51,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	// A '(' after an expression is a call
+	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	return p
 }
 
@@ -69,8 +77,12 @@ func (p *Parser) ParseProgram() *ast.Program {
 			// Add the parsed statement to the []Statement
 			program.Statements = append(program.Statements, stmt)
 		}
+		if p.mode&StopAtFirstError != 0 && len(p.errors) != 0 {
+			break
+		}
 		p.nextToken()
 	}
+	program.Comments = p.l.Comments()
 	return program
 }
 
@@ -78,8 +90,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 func (p *Parser) expectPeek(tt token.TokenType) bool {
 	match := p.peekToken.Type == tt
 	if !match {
-		msg := fmt.Sprintf("Expected next token to be %s. Got %s instead", tt, p.peekToken.Type)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.peekToken.Pos, "Expected next token to be %s. Got %s instead", tt, p.peekToken.Type)
 	} else {
 		p.nextToken()
 	}
@@ -98,10 +109,15 @@ func (p *Parser) peekTokenIs(tt token.TokenType) bool {
 
 // parseStatement parses Statements
 func (p *Parser) parseStatement() ast.Statement {
+	defer p.untrace(p.trace("parseStatement"))
 	switch p.currentToken.Type {
 	case token.LET:
 		// parse the LetStatement if the current token is token.LET
-		return p.parseLetStatement()
+		// A nil *ast.LetStatement must not be returned as a non-nil ast.Statement
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
 		// parse the ReturnStatement if the current token is token.RETURN
 		return p.parseReturnStatement()
@@ -112,6 +128,7 @@ func (p *Parser) parseStatement() ast.Statement {
 
 // parseLetStatement parses LET statements
 func (p *Parser) parseLetStatement() *ast.LetStatement {
+	defer p.untrace(p.trace("parseLetStatement"))
 	stmt := &ast.LetStatement{Token: p.currentToken}
 	// Check if the next token is token.IDENT
 	if !p.expectPeek(token.IDENT) {
@@ -121,73 +138,62 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	// p.nextToken()
 	// Set the Name variable to a new Identifier using the currentToken
 	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	// An optional type annotation follows a ':'
+	if p.peekTokenIs(token.COLON) {
+		if stmt.Type = p.parseAnnotation(); stmt.Type == nil {
+			return nil
+		}
+	}
 	// Check if the next token is token.ASSIGN
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
-	// Move to the next token
-	// p.nextToken()
-	// TODO: Skip all the expressions for now
-	// Skip all expressions until you get to the SEMICOLON token
-	for p.currentToken.Type != token.SEMICOLON && !p.currentTokenIs(token.SEMICOLON) {
+	// Move past the token.ASSIGN token to the start of the expression
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	defer p.untrace(p.trace("parseReturnStatement"))
 	stmt := &ast.ReturnStatement{Token: p.currentToken}
 	// move to the next token. past the token.RETURN token
 	p.nextToken()
-	// TODO:Skip all the expressions until you get to the token.RETURN token
-	if !p.currentTokenIs(token.SEMICOLON) {
+	stmt.ReturnValue = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 
 // Constants used to compare the precedence of different operators
+// They are the precedences of the token package
 const (
-	_ int = iota
-	LOWEST
-	EQUALS      // == !=
-	LESSGREATER // < >
-	SUM         // + -
-	PRODUCT     // * /
-	PREFIX      // ! + -
-	CALL        // fn()
+	LOWEST      = token.LOWEST
+	EQUALS      = token.EQUALS      // == !=
+	LESSGREATER = token.LESSGREATER // < >
+	SUM         = token.SUM         // + -
+	PRODUCT     = token.PRODUCT     // * /
+	PREFIX      = token.PREFIX      // ! + -
+	CALL        = token.CALL        // fn()
 )
 
-// The precedence table
-var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-}
-
 // peekPrecedence is a helper function that returns the precedence of the next token
 func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return p.peekToken.Type.Precedence()
 }
 
 // currentPrecedence is a helper function that returns the precedence of the current token
 func (p *Parser) currentPrecedence() int {
-	if p, ok := precedences[p.currentToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return p.currentToken.Type.Precedence()
 }
 
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	stmt := &ast.ExpressionStatement{}
+	defer p.untrace(p.trace("parseExpressionStatement"))
+	stmt := &ast.ExpressionStatement{Token: p.currentToken}
 	stmt.Expression = p.parseExpression(LOWEST)
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -195,12 +201,23 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
-// TODO: I don't understand how this part works
+// parseExpression parses an expression whose operators all bind tighter than precedence
+// The prefix parsing function of the current token parses the start of the expression. As long as the
+// peek token is an infix operator with a higher precedence, it takes the expression so far as its left
+// operand. SetTrace shows this step by step
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	defer p.untrace(p.trace("parseExpression"))
 	// Parse the next expression using the prefix-parsing-function eg returns integer or identifier
 	prefixFn := p.prefixParsingFunctions[p.currentToken.Type]
+	if prefixFn == nil {
+		p.noPrefixParsingFunctionError(p.currentToken.Type)
+		return nil
+	}
+	if p.tracer != nil {
+		p.tracef("prefix: %s starts the expression", describe(p.currentToken.Type, p.currentToken.Literal))
+	}
 	leftExpression := prefixFn()
-	for precedence < p.peekPrecedence() {
+	for p.continueExpression(precedence) {
 		// returns an ast.InfixExpression for 1 + 2
 		infixFn := p.infixParsingFunctions[p.peekToken.Type]
 		p.nextToken()
@@ -209,16 +226,44 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExpression
 }
 
+// continueExpression reports whether the peek token is an infix operator that binds tighter than precedence
+// If it does, it takes the expression parsed so far as its left operand
+func (p *Parser) continueExpression(precedence int) bool {
+	peek := p.peekPrecedence()
+	more := precedence < peek
+	if p.tracer != nil {
+		verdict := "stop, the expression so far is complete"
+		if more {
+			verdict = "continue, the peek token takes the expression so far as its left operand"
+		}
+		p.tracef("compare: %s < %s (peek %s) is %t: %s", precedenceName(precedence), precedenceName(peek), describe(p.peekToken.Type, p.peekToken.Literal), more, verdict)
+	}
+	return more
+}
+
+// noPrefixParsingFunctionError is a helper function that records a token that cannot start an expression
+func (p *Parser) noPrefixParsingFunctionError(tt token.TokenType) {
+	p.errorf(p.currentToken.Pos, "No prefix parsing function for %s found", tt)
+}
+
+// errorf is a helper function that records an error at pos
+func (p *Parser) errorf(pos token.Position, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	p.errors = append(p.errors, msg)
+	p.errorList.Add(pos, msg)
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
+	defer p.untrace(p.trace("parseIdentifier"))
 	return &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseIntegerLiteral"))
 	lit := &ast.IntegerLiteral{Token: p.currentToken}
 	val, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("Could not parse %s as an integer", p.currentToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.currentToken.Pos, "Could not parse %s as an integer", p.currentToken.Literal)
 		return nil
 	}
 	lit.Value = val
@@ -226,6 +271,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
+	defer p.untrace(p.trace("parsePrefixExpression"))
 	expression := &ast.PrefixExpression{
 		Token:    p.currentToken,
 		Operator: p.currentToken.Literal,
@@ -236,6 +282,7 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseInfixExpression"))
 	expression := &ast.InfixExpression{
 		Token:    p.currentToken,
 		Operator: p.currentToken.Literal,
@@ -250,10 +297,12 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 }
 
 func (p *Parser) parseBoolean() ast.Expression {
+	defer p.untrace(p.trace("parseBoolean"))
 	return &ast.Boolean{Token: p.currentToken, Value: p.currentTokenIs(token.TRUE)}
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	defer p.untrace(p.trace("parseGroupedExpression"))
 	p.nextToken()
 	expr := p.parseExpression(LOWEST)
 	if !p.expectPeek(token.RPAREN) {
@@ -263,6 +312,7 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
+	defer p.untrace(p.trace("parseIfExpression"))
 	expr := &ast.IfExpression{Token: p.currentToken}
 	if !p.expectPeek(token.LPAREN) {
 		return nil
@@ -294,17 +344,23 @@ func (p *Parser) parseIfExpression() ast.Expression {
 }
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	defer p.untrace(p.trace("parseBlockStatement"))
 	block := &ast.BlockStatement{Token: p.currentToken}
 	block.Statements = []ast.Statement{}
 
 	p.nextToken()
-	for !p.currentTokenIs(token.LBRACE) && !p.currentTokenIs(token.EOF) {
+	for !p.currentTokenIs(token.RBRACE) && !p.currentTokenIs(token.EOF) {
 		stmt := p.parseStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	if !p.currentTokenIs(token.RBRACE) {
+		p.errorf(p.currentToken.Pos, "Expected %s to close the block. Got %s instead", token.RBRACE, p.currentToken.Type)
+		return block
+	}
+	block.Rbrace = p.currentToken.Pos
 	return block
 }
 
@@ -317,12 +373,44 @@ func (p *Parser) registerInfix(tt token.TokenType, fn infixParsingFunction) {
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseFunctionLiteral"))
 	lit := &ast.FunctionLiteral{Token: p.currentToken}
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	p.nextToken()
-	lit.Parameters = p.parseFunctionParameters()
+	if lit.Parameters = p.parseFunctionParameters(); lit.Parameters == nil {
+		return nil
+	}
+	// An optional result type follows a '->'
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		p.nextToken()
+		if lit.ReturnType = p.parseType(); lit.ReturnType == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	lit.Body = p.parseBlockStatement()
+	return lit
+}
+
+// parseMacroLiteral parses a macro. Its parameters stand for syntax, so they have no type annotations
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseMacroLiteral"))
+	lit := &ast.MacroLiteral{Token: p.currentToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	if lit.Parameters = p.parseFunctionParameters(); lit.Parameters == nil {
+		return nil
+	}
+	for _, pr := range lit.Parameters {
+		if pr.Type != nil {
+			p.errorf(pr.Type.Pos(), "Unexpected type annotation on macro parameter %s", pr.Value)
+		}
+	}
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -331,6 +419,7 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 }
 
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	defer p.untrace(p.trace("parseFunctionParameters"))
 	identifiers := []*ast.Identifier{}
 
 	// check if there are not parameters
@@ -340,22 +429,78 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	}
 	p.nextToken()
 
-	ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	ident := p.parseParameter()
+	if ident == nil {
+		return nil
+	}
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		ident = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		if ident = p.parseParameter(); ident == nil {
+			return nil
+		}
 		identifiers = append(identifiers, ident)
 	}
 
-	if !p.peekTokenIs(token.SEMICOLON) {
+	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 	return identifiers
 }
 
+// parseParameter parses a function parameter and its optional type annotation
+func (p *Parser) parseParameter() *ast.Identifier {
+	if !p.currentTokenIs(token.IDENT) {
+		p.errorf(p.currentToken.Pos, "Expected a parameter name. Got %s instead", p.currentToken.Type)
+		return nil
+	}
+	ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	if p.peekTokenIs(token.COLON) {
+		if ident.Type = p.parseAnnotation(); ident.Type == nil {
+			return nil
+		}
+	}
+	return ident
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseCallExpression"))
+	expr := &ast.CallExpression{Token: p.currentToken, Function: function}
+	expr.Arguments = p.parseCallArguments()
+	if expr.Arguments == nil {
+		return nil
+	}
+	expr.Rparen = p.currentToken.Pos
+	return expr
+}
+
+// parseCallArguments parses the comma separated arguments of a call up to the ')' token
+func (p *Parser) parseCallArguments() []ast.Expression {
+	defer p.untrace(p.trace("parseCallArguments"))
+	args := []ast.Expression{}
+
+	// check if there are no arguments
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return args
+	}
+	p.nextToken()
+	args = append(args, p.parseExpression(LOWEST))
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		args = append(args, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return args
+}
+
 // The two types of functions needed for a pratt parser ie. a Prefix parsing function and an Infix parsing function
 type (
 	prefixParsingFunction func() ast.Expression               // Returns an ast.Expression object
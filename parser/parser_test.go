@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"let f = fn(x) { x; };", nil},
+		{"let f = fn(x: int, y) { if (x) { y } else { 1 } };", nil},
+		// A block needs its '}'
+		{"let f = fn(x) { x", []string{"1:18: Expected } to close the block. Got EOF instead"}},
+		{"if (x) { 1 } else { 2", []string{"1:22: Expected } to close the block. Got EOF instead"}},
+		{"let m = macro(x) {\n", []string{"2:1: Expected } to close the block. Got EOF instead"}},
+		{"fn() { fn() { 1 }", []string{"1:18: Expected } to close the block. Got EOF instead"}},
+		// A parameter is a name
+		{"fn(1, 2) { 1 };", []string{
+			"1:4: Expected a parameter name. Got INT instead",
+			"1:5: No prefix parsing function for , found",
+			"1:8: No prefix parsing function for ) found",
+			"1:10: No prefix parsing function for { found",
+			"1:14: No prefix parsing function for } found",
+		}},
+		{"fn(x, true) { x }", []string{
+			"1:7: Expected a parameter name. Got TRUE instead",
+			"1:11: No prefix parsing function for ) found",
+			"1:13: No prefix parsing function for { found",
+			"1:17: No prefix parsing function for } found",
+		}},
+		{"macro(x,) { x }", []string{
+			"1:9: Expected a parameter name. Got ) instead",
+			"1:11: No prefix parsing function for { found",
+			"1:15: No prefix parsing function for } found",
+		}},
+	}
+	for _, tt := range tests {
+		_, err := ParseFile("", tt.src, AllErrors)
+		var got []string
+		if list, ok := err.(ErrorList); ok {
+			for _, e := range list {
+				got = append(got, e.Error())
+			}
+		} else if err != nil {
+			t.Fatalf("%q: %v is not an ErrorList", tt.src, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q:\ngot  %q\nwant %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The names of the precedences, used in the trace
+var precedenceNames = map[int]string{
+	LOWEST:      "LOWEST",
+	EQUALS:      "EQUALS",
+	LESSGREATER: "LESSGREATER",
+	SUM:         "SUM",
+	PRODUCT:     "PRODUCT",
+	PREFIX:      "PREFIX",
+	CALL:        "CALL",
+}
+
+// SetTrace turns on tracing. Every entry to and exit from a parsing function is written to w,
+// indented by nesting depth, together with the current and peek tokens. parseExpression also
+// writes every precedence comparison it makes. A nil w turns tracing off
+func (p *Parser) SetTrace(w io.Writer) {
+	p.tracer = w
+	p.traceDepth = 0
+}
+
+// trace writes the entry to the parsing function name and returns name for untrace
+// It is used as: defer p.untrace(p.trace("parseIfExpression"))
+func (p *Parser) trace(name string) string {
+	if p.tracer != nil {
+		p.tracef("BEGIN %s (current: %s, peek: %s)", name, describe(p.currentToken.Type, p.currentToken.Literal), describe(p.peekToken.Type, p.peekToken.Literal))
+		p.traceDepth++
+	}
+	return name
+}
+
+// untrace writes the exit from the parsing function name
+func (p *Parser) untrace(name string) {
+	if p.tracer != nil {
+		p.traceDepth--
+		p.tracef("END %s (current: %s)", name, describe(p.currentToken.Type, p.currentToken.Literal))
+	}
+}
+
+// tracef writes a line of the trace at the current depth
+func (p *Parser) tracef(format string, args ...interface{}) {
+	if p.tracer == nil {
+		return
+	}
+	fmt.Fprintf(p.tracer, "%s%s\n", strings.Repeat("\t", p.traceDepth), fmt.Sprintf(format, args...))
+}
+
+// precedenceName is a helper function that returns the name of a precedence
+func precedenceName(precedence int) string {
+	if name, ok := precedenceNames[precedence]; ok {
+		return name
+	}
+	return fmt.Sprint(precedence)
+}
+
+// describe is a helper function that formats a token for the trace
+func describe(tt interface{}, literal string) string {
+	if fmt.Sprint(tt) == literal {
+		return fmt.Sprintf("%q", literal)
+	}
+	return fmt.Sprintf("%s %q", tt, literal)
+}
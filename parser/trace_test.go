@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"monkey/lexer"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	var b strings.Builder
+	p := New(lexer.New("-a + 2 * 3;"))
+	p.SetTrace(&b)
+	p.ParseProgram()
+	want := `
+BEGIN parseStatement (current: "-", peek: IDENT "a")
+	BEGIN parseExpressionStatement (current: "-", peek: IDENT "a")
+		BEGIN parseExpression (current: "-", peek: IDENT "a")
+			prefix: "-" starts the expression
+			BEGIN parsePrefixExpression (current: "-", peek: IDENT "a")
+				BEGIN parseExpression (current: IDENT "a", peek: "+")
+					prefix: IDENT "a" starts the expression
+					BEGIN parseIdentifier (current: IDENT "a", peek: "+")
+					END parseIdentifier (current: IDENT "a")
+					compare: PREFIX < SUM (peek "+") is false: stop, the expression so far is complete
+				END parseExpression (current: IDENT "a")
+			END parsePrefixExpression (current: IDENT "a")
+			compare: LOWEST < SUM (peek "+") is true: continue, the peek token takes the expression so far as its left operand
+			BEGIN parseInfixExpression (current: "+", peek: INT "2")
+				BEGIN parseExpression (current: INT "2", peek: "*")
+					prefix: INT "2" starts the expression
+					BEGIN parseIntegerLiteral (current: INT "2", peek: "*")
+					END parseIntegerLiteral (current: INT "2")
+					compare: SUM < PRODUCT (peek "*") is true: continue, the peek token takes the expression so far as its left operand
+					BEGIN parseInfixExpression (current: "*", peek: INT "3")
+						BEGIN parseExpression (current: INT "3", peek: ";")
+							prefix: INT "3" starts the expression
+							BEGIN parseIntegerLiteral (current: INT "3", peek: ";")
+							END parseIntegerLiteral (current: INT "3")
+							compare: PRODUCT < LOWEST (peek ";") is false: stop, the expression so far is complete
+						END parseExpression (current: INT "3")
+					END parseInfixExpression (current: INT "3")
+					compare: SUM < LOWEST (peek ";") is false: stop, the expression so far is complete
+				END parseExpression (current: INT "3")
+			END parseInfixExpression (current: INT "3")
+			compare: LOWEST < LOWEST (peek ";") is false: stop, the expression so far is complete
+		END parseExpression (current: INT "3")
+	END parseExpressionStatement (current: ";")
+END parseStatement (current: ";")
+`
+	if got := b.String(); got != strings.TrimPrefix(want, "\n") {
+		t.Errorf("got\n%swant%s", got, want)
+	}
+
+	// Turning tracing off stops the trace
+	b.Reset()
+	p = New(lexer.New("1 + 2;"))
+	p.SetTrace(&b)
+	p.SetTrace(nil)
+	p.ParseProgram()
+	if b.Len() != 0 {
+		t.Errorf("tracing was turned off, but the trace is\n%s", b.String())
+	}
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"monkey/ast"
+	"monkey/token"
+)
+
+// The grammar of type annotations:
+//
+//	Type     = Name [ "[" TypeList "]" ] | "[" Type "]" | "fn" "(" [ TypeList ] ")" "->" Type
+//	TypeList = Type { "," Type }
+//
+// A name with arguments, such as map[string, int], is a generic type and [int] is a list
+
+// parseAnnotation parses the ':' after the peek token and the type after it
+func (p *Parser) parseAnnotation() ast.TypeExpr {
+	defer p.untrace(p.trace("parseAnnotation"))
+	p.nextToken()
+	p.nextToken()
+	return p.parseType()
+}
+
+// parseType parses the type that starts at the current token and stops on its last token
+// It returns nil after reporting an error if there is no type there
+func (p *Parser) parseType() ast.TypeExpr {
+	defer p.untrace(p.trace("parseType"))
+	switch p.currentToken.Type {
+	case token.IDENT:
+		t := &ast.NamedType{Token: p.currentToken, Name: p.currentToken.Literal}
+		if !p.peekTokenIs(token.LBRACKET) {
+			return t
+		}
+		p.nextToken()
+		if t.Args = p.parseTypeList(token.RBRACKET); t.Args == nil {
+			return nil
+		}
+		if len(t.Args) == 0 {
+			p.errorf(p.currentToken.Pos, "Expected type arguments for %s", t.Name)
+			return nil
+		}
+		t.Rbracket = p.currentToken.Pos
+		return t
+	case token.LBRACKET:
+		t := &ast.ListType{Token: p.currentToken}
+		p.nextToken()
+		if t.Elem = p.parseType(); t.Elem == nil {
+			return nil
+		}
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		t.Rbracket = p.currentToken.Pos
+		return t
+	case token.FUNCTION:
+		t := &ast.FunctionType{Token: p.currentToken}
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		if t.Params = p.parseTypeList(token.RPAREN); t.Params == nil {
+			return nil
+		}
+		t.Rparen = p.currentToken.Pos
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+		if t.Result = p.parseType(); t.Result == nil {
+			return nil
+		}
+		return t
+	}
+	p.errorf(p.currentToken.Pos, "Expected a type. Got %s instead", p.currentToken.Type)
+	return nil
+}
+
+// parseTypeList parses the comma separated types after the current opening token up to the closing one
+// It returns nil after an error, and an empty list if there are no types
+func (p *Parser) parseTypeList(closing token.TokenType) []ast.TypeExpr {
+	defer p.untrace(p.trace("parseTypeList"))
+	types := []ast.TypeExpr{}
+	if p.peekTokenIs(closing) {
+		p.nextToken()
+		return types
+	}
+	for {
+		p.nextToken()
+		t := p.parseType()
+		if t == nil {
+			return nil
+		}
+		types = append(types, t)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(closing) {
+		return nil
+	}
+	return types
+}
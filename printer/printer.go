@@ -0,0 +1,245 @@
+// Package printer prints syntax trees as Monkey source
+//
+// Unlike the String methods of the ast nodes, which are meant for debugging, the output of the
+// printer always parses back to an equal tree: parsing the printed source of a tree produced by
+// parser.ParseProgram gives a tree that is ast.Equal to it when positions are ignored
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"monkey/ast"
+	"monkey/token"
+	"strconv"
+	"strings"
+)
+
+// Operator precedences, those of the token package and atom for what binds tightest
+const (
+	lowest = token.LOWEST
+	prefix = token.PREFIX
+	call   = token.CALL
+	atom   = token.CALL + 1 // literals, identifiers and anything that is parsed by a prefix parsing function on its own
+)
+
+// InfixPrecedence returns the precedence of an infix operator
+// The type of an operator token is the operator itself
+func InfixPrecedence(operator string) int {
+	return token.TokenType(operator).Precedence()
+}
+
+// Fprint writes the source of node to w
+// Statements are printed one per line and blocks are indented with tabs
+func Fprint(w io.Writer, node ast.Node) error {
+	p := &printer{}
+	p.node(node)
+	_, err := w.Write(p.out.Bytes())
+	return err
+}
+
+// String returns the source of node
+func String(node ast.Node) string {
+	p := &printer{}
+	p.node(node)
+	return p.out.String()
+}
+
+type printer struct {
+	out    bytes.Buffer
+	indent int
+}
+
+func (p *printer) print(s string) {
+	p.out.WriteString(s)
+}
+
+// newline starts a new line at the current indentation
+func (p *printer) newline() {
+	p.print("\n")
+	p.print(strings.Repeat("\t", p.indent))
+}
+
+func (p *printer) node(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Program:
+		for i, stmt := range n.Statements {
+			if i > 0 {
+				p.print("\n")
+			}
+			p.statement(stmt)
+		}
+		if len(n.Statements) > 0 {
+			p.print("\n")
+		}
+	case ast.Statement:
+		p.statement(n)
+	case ast.Expression:
+		p.expression(n, lowest)
+	case ast.TypeExpr:
+		// The String of a type expression is its source
+		p.print(n.String())
+	default:
+		panic(fmt.Sprintf("printer: unexpected node type %T", node))
+	}
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		p.print("let ")
+		p.print(s.Name.Value)
+		if s.Type != nil {
+			p.print(": " + s.Type.String())
+		}
+		p.print(" = ")
+		p.expression(s.Value, lowest)
+		p.print(";")
+	case *ast.ReturnStatement:
+		p.print("return ")
+		p.expression(s.ReturnValue, lowest)
+		p.print(";")
+	case *ast.ExpressionStatement:
+		// The parser records the first token of the statement. Keep a leading paren
+		// that the expression would not print by itself, so the statement parses back the same
+		if s.Token.Literal == "(" && !StartsWithParen(s.Expression) {
+			p.print("(")
+			p.expression(s.Expression, lowest)
+			p.print(")")
+		} else {
+			p.expression(s.Expression, lowest)
+		}
+		// Without the semicolon the next statement could continue the expression, eg. -1
+		p.print(";")
+	case *ast.BlockStatement:
+		p.block(s)
+	default:
+		panic(fmt.Sprintf("printer: unexpected statement type %T", stmt))
+	}
+}
+
+func (p *printer) block(block *ast.BlockStatement) {
+	if len(block.Statements) == 0 {
+		p.print("{}")
+		return
+	}
+	p.print("{")
+	p.indent++
+	for _, stmt := range block.Statements {
+		p.newline()
+		p.statement(stmt)
+	}
+	p.indent--
+	p.newline()
+	p.print("}")
+}
+
+// expression prints expr, in parentheses if its precedence is lower than the context requires
+func (p *printer) expression(expr ast.Expression, context int) {
+	if Precedence(expr) < context {
+		p.print("(")
+		p.expression(expr, lowest)
+		p.print(")")
+		return
+	}
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		p.print(e.Value)
+	case *ast.IntegerLiteral:
+		p.print(IntegerSource(e))
+	case *ast.Boolean:
+		p.print(strconv.FormatBool(e.Value))
+	case *ast.PrefixExpression:
+		p.print(e.Operator)
+		p.expression(e.Right, prefix)
+	case *ast.InfixExpression:
+		prec := InfixPrecedence(e.Operator)
+		// All operators are left associative, so an operand of the same precedence needs parentheses on the right only
+		p.expression(e.Left, prec)
+		p.print(" " + e.Operator + " ")
+		p.expression(e.Right, prec+1)
+	case *ast.IfExpression:
+		p.print("if (")
+		p.expression(e.Condition, lowest)
+		p.print(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.print(" else ")
+			p.block(e.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		params := make([]string, len(e.Parameters))
+		for i, pr := range e.Parameters {
+			// The String of a parameter is its source, with its type annotation
+			params[i] = pr.String()
+		}
+		p.print("fn(" + strings.Join(params, ", ") + ") ")
+		if e.ReturnType != nil {
+			p.print("-> " + e.ReturnType.String() + " ")
+		}
+		p.block(e.Body)
+	case *ast.MacroLiteral:
+		params := make([]string, len(e.Parameters))
+		for i, pr := range e.Parameters {
+			params[i] = pr.String()
+		}
+		p.print("macro(" + strings.Join(params, ", ") + ") ")
+		p.block(e.Body)
+	case *ast.CallExpression:
+		p.expression(e.Function, call)
+		p.print("(")
+		for i, arg := range e.Arguments {
+			if i > 0 {
+				p.print(", ")
+			}
+			p.expression(arg, lowest)
+		}
+		p.print(")")
+	default:
+		panic(fmt.Sprintf("printer: unexpected expression type %T", expr))
+	}
+}
+
+// Precedence returns the precedence of the operator at the top of expr
+// It is token.CALL + 1 for literals, identifiers and the other expressions that bind tightest
+func Precedence(expr ast.Expression) int {
+	switch e := expr.(type) {
+	case *ast.InfixExpression:
+		return InfixPrecedence(e.Operator)
+	case *ast.PrefixExpression:
+		return prefix
+	case *ast.CallExpression:
+		return call
+	case *ast.IntegerLiteral:
+		if e.Value < 0 {
+			// A negative value is printed with a minus, which parses as a prefix expression
+			return prefix
+		}
+	}
+	return atom
+}
+
+// StartsWithParen reports whether the printed form of expr starts with a '('
+func StartsWithParen(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.InfixExpression:
+		return Precedence(e.Left) < InfixPrecedence(e.Operator) || StartsWithParen(e.Left)
+	case *ast.CallExpression:
+		return Precedence(e.Function) < call || StartsWithParen(e.Function)
+	}
+	return false
+}
+
+// IntegerSource returns the source of an integer literal
+// The literal of its token is kept when it still has the literal's value, so "007" stays "007"
+func IntegerSource(lit *ast.IntegerLiteral) string {
+	if v, err := strconv.ParseInt(lit.Token.Literal, 0, 64); err == nil && v == lit.Value {
+		return lit.Token.Literal
+	}
+	if lit.Value == math.MinInt64 {
+		// The magnitude of the smallest integer is too large to be written as a literal
+		return "(-9223372036854775807 - 1)"
+	}
+	return strconv.FormatInt(lit.Value, 10)
+}
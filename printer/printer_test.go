@@ -0,0 +1,113 @@
+package printer
+
+import (
+	"math"
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/token"
+	"testing"
+)
+
+// roundTrips are sources whose printed form must parse back to an equal tree
+// Comments are not part of the tree, so wherever they are they do not change it
+var roundTrips = []string{
+	"let x = 5;",
+	"// leading\nlet a = 1 - (2 - 3); // trailing\nlet b = (1 - 2) - 3;",
+	// Operator precedence
+	"1 + 2 * 3; (1 + 2) * 3; 1 * 2 + 3; 1 * (2 + 3);",
+	"a / b / c; a / (b / c); a == b != c; a == (b != c); a < b == c > d;",
+	"-a * b; -(a * b); !-a; -(-a); --a; !(a == b); -a * b == !c;",
+	"f(1)(2); (f(1))(2); f(g(1), h(2, 3)) + 4;",
+	// Nested ifs and functions, with a comment in a block
+	`let f = fn(x: int, g: fn(int) -> bool) -> [int] {
+	// inside
+	if (g(x)) { if (x > 1) { return -x; } else { !g(x) } } else { fn(y) { y }(x) }
+};`,
+	"(if (a) { f } else { f })(1, fn() { 2 });",
+	"(fn(x) { x })(3) * -(4 + 5);",
+	"if (x) { } else { }; fn() { };",
+	"let adder = fn(x) { fn(y) { fn(z) { x + y + z } } }; adder(1)(2)(3);",
+	// A statement that starts with a paren keeps it
+	"(2); (a) + 1; (1 + 2) * 3;",
+	"return if (a) { 1 } else { 2 };",
+	"let m = macro(a, b) { quote(unquote(a) + unquote(b)) }; m(1, 2);",
+	"let l: [map[int, [a]]] = 1; let g: fn() -> fn(int, bool) -> int = 2;",
+	"007; 0x1F;",
+}
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	program, err := parser.ParseFile("", src, 0)
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	return program
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, src := range roundTrips {
+		program := parse(t, src)
+		printed := String(program)
+		again := parse(t, printed)
+		if !ast.Equal(program, again, ast.IgnorePositions) {
+			t.Errorf("%q printed as\n%s\nwhich parses to a different tree", src, printed)
+		}
+		// Printing is stable
+		if twice := String(again); twice != printed {
+			t.Errorf("%q printed as\n%s\nthen as\n%s", src, printed, twice)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"let a = (1 - 2) - 3;", "let a = 1 - 2 - 3;\n"},
+		{"let a = 1 - (2 - 3);", "let a = 1 - (2 - 3);\n"},
+		{"-(-a);", "--a;\n"},
+		{"if (x) { 1 } else { if (y) { 2 } };", "if (x) {\n\t1;\n} else {\n\tif (y) {\n\t\t2;\n\t};\n};\n"},
+		{"fn(a: int) -> bool { };", "fn(a: int) -> bool {};\n"},
+		{"(2);", "(2);\n"},
+	}
+	for _, tt := range tests {
+		if got := String(parse(t, tt.src)); got != tt.want {
+			t.Errorf("%q:\ngot\n%s\nwant\n%s", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestBuiltTrees prints trees that the parser does not produce, such as those of rewrites
+func TestBuiltTrees(t *testing.T) {
+	integer := func(v int64) *ast.IntegerLiteral {
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT}, Value: v}
+	}
+	ident := func(name string) *ast.Identifier {
+		return &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	}
+	infix := func(left ast.Expression, op string, right ast.Expression) *ast.InfixExpression {
+		return &ast.InfixExpression{Token: token.Token{Type: token.TokenType(op), Literal: op}, Operator: op, Left: left, Right: right}
+	}
+	tests := []struct {
+		expr ast.Expression
+		want string
+	}{
+		{integer(-5), "-5"},
+		{infix(ident("x"), "-", integer(-5)), "x - -5"},
+		{infix(integer(-5), "*", ident("x")), "-5 * x"},
+		{&ast.PrefixExpression{Token: token.Token{Type: token.MINUS, Literal: "-"}, Operator: "-", Right: integer(-5)}, "--5"},
+		{integer(math.MinInt64), "(-9223372036854775807 - 1)"},
+		{infix(integer(math.MinInt64), "/", integer(2)), "(-9223372036854775807 - 1) / 2"},
+		{&ast.CallExpression{Token: token.Token{Type: token.LPAREN, Literal: "("}, Function: integer(-1), Arguments: []ast.Expression{}}, "(-1)()"},
+	}
+	for _, tt := range tests {
+		got := String(tt.expr)
+		if got != tt.want {
+			t.Errorf("got %s, want %s", got, tt.want)
+		}
+		// The source computes the same value, though a negative literal parses as a prefix expression
+		if _, err := parser.ParseExpr(got); err != nil {
+			t.Errorf("%s: %v", got, err)
+		}
+	}
+}
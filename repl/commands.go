@@ -0,0 +1,151 @@
+package repl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"sort"
+	"strings"
+)
+
+// Command is a REPL meta-command. It is invoked by typing ':' followed by its name
+type Command struct {
+	Name  string                              // The name without the leading ':'
+	Usage string                              // A short synopsis eg. ":load <file>"
+	Help  string                              // A one line description
+	Run   func(s *Session, args string) error // Called with everything after the name
+}
+
+// The command registry
+var commands = map[string]*Command{}
+
+// Register adds a command to the registry so it is available in every session
+// Registering a name that is already taken replaces the earlier command
+func Register(cmd *Command) {
+	commands[cmd.Name] = cmd
+}
+
+// Commands returns the registered commands sorted by name
+func Commands() []*Command {
+	cmds := make([]*Command, 0, len(commands))
+	for _, cmd := range commands {
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	return cmds
+}
+
+func init() {
+	Register(&Command{Name: "help", Usage: ":help", Help: "list the available commands", Run: helpCommand})
+	Register(&Command{Name: "quit", Usage: ":quit", Help: "leave the REPL", Run: quitCommand})
+	Register(&Command{Name: "tokens", Usage: ":tokens <src>", Help: "print the token stream of src", Run: tokensCommand})
+	Register(&Command{Name: "ast", Usage: ":ast <src>", Help: "print the syntax tree of src", Run: astCommand})
+	Register(&Command{Name: "time", Usage: ":time", Help: "toggle printing how long each input takes", Run: timeCommand})
+	Register(&Command{Name: "env", Usage: ":env", Help: "list the let statements that bound the names of the session", Run: envCommand})
+	Register(&Command{Name: "load", Usage: ":load <file>", Help: "parse a file into the session as if it was typed", Run: loadCommand})
+	Register(&Command{Name: "save", Usage: ":save <file>", Help: "write the session history to a file", Run: saveCommand})
+}
+
+// runCommand looks up the command named in line and runs it
+func (s *Session) runCommand(line string) {
+	name, args := splitCommand(line)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(s.out, "unknown command :%s. Type :help for a list of commands\n", name)
+		return
+	}
+	if err := cmd.Run(s, args); err != nil {
+		fmt.Fprintf(s.out, "%s: %s\n", cmd.Usage, err)
+	}
+}
+
+// splitCommand is a helper function that splits ":name args" into its name and arguments
+func splitCommand(line string) (string, string) {
+	line = strings.TrimPrefix(line, ":")
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		return line[:i], strings.TrimSpace(line[i:])
+	}
+	return line, ""
+}
+
+func helpCommand(s *Session, args string) error {
+	for _, cmd := range Commands() {
+		fmt.Fprintf(s.out, "%-16s %s\n", cmd.Usage, cmd.Help)
+	}
+	return nil
+}
+
+func quitCommand(s *Session, args string) error {
+	s.quit = true
+	return nil
+}
+
+func tokensCommand(s *Session, args string) error {
+	for it := lexer.New(args).Iterator(); it.Next(); {
+		tok := it.Token()
+		fmt.Fprintf(s.out, "%-10s %q\n", tok.Type, tok.Literal)
+	}
+	return nil
+}
+
+func astCommand(s *Session, args string) error {
+	p := parser.New(lexer.New(args))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return nil
+	}
+	ast.Fprint(s.out, program)
+	return nil
+}
+
+func timeCommand(s *Session, args string) error {
+	s.timing = !s.timing
+	if s.timing {
+		fmt.Fprintln(s.out, "timing on")
+	} else {
+		fmt.Fprintln(s.out, "timing off")
+	}
+	return nil
+}
+
+func envCommand(s *Session, args string) error {
+	for _, ls := range s.Bindings() {
+		if ls.Value != nil {
+			fmt.Fprintf(s.out, "%s = %s\n", ls.Name.Value, ls.Value.String())
+		} else {
+			fmt.Fprintln(s.out, ls.Name.Value)
+		}
+	}
+	return nil
+}
+
+func loadCommand(s *Session, args string) error {
+	if args == "" {
+		return fmt.Errorf("missing file name")
+	}
+	src, err := ioutil.ReadFile(args)
+	if err != nil {
+		return err
+	}
+	s.history = append(s.history, string(src))
+	s.input(string(src))
+	return nil
+}
+
+func saveCommand(s *Session, args string) error {
+	if args == "" {
+		return fmt.Errorf("missing file name")
+	}
+	src := strings.Join(s.history, "\n")
+	if src != "" {
+		src += "\n"
+	}
+	if err := ioutil.WriteFile(args, []byte(src), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "saved %d entries to %s\n", len(s.history), args)
+	return nil
+}
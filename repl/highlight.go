@@ -0,0 +1,50 @@
+package repl
+
+import (
+	"monkey/lexer"
+	"monkey/token"
+	"strings"
+)
+
+// ANSI escape sequences used to colour the input
+const (
+	colorReset   = "\x1b[0m"
+	colorKeyword = "\x1b[1;34m"
+	colorNumber  = "\x1b[36m"
+	colorIllegal = "\x1b[31m"
+)
+
+// highlight returns src with every token coloured according to its token type
+// Everything between the tokens, such as whitespace, is copied unchanged
+func highlight(src string) string {
+	var out strings.Builder
+	offset := 0
+	for it := lexer.New(src).Iterator(); it.Next(); {
+		tok := it.Token()
+		out.WriteString(src[offset:tok.Pos.Offset])
+		color := tokenColor(tok.Type)
+		if color != "" {
+			out.WriteString(color)
+		}
+		out.WriteString(tok.Literal)
+		if color != "" {
+			out.WriteString(colorReset)
+		}
+		offset = tok.End().Offset
+	}
+	out.WriteString(src[offset:])
+	return out.String()
+}
+
+// tokenColor is a helper function that returns the colour of a token type, or "" for the default colour
+func tokenColor(tt token.TokenType) string {
+	switch {
+	case tt.IsKeyword():
+		return colorKeyword
+	case tt == token.INT:
+		return colorNumber
+	case tt == token.ILLEGAL:
+		return colorIllegal
+	}
+	return ""
+}
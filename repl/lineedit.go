@@ -0,0 +1,337 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// errInterrupted is returned by ReadLine when the user presses Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// Control keys understood by the line editor
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyLineFeed  = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// LineEditor reads lines from a terminal in raw mode
+// It supports cursor movement, history navigation, reverse history search and tab completion
+type LineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	prompt   string
+	History  []string                     // The lines entered so far, oldest first
+	Complete func(prefix string) []string // Returns the completions of the word before the cursor
+
+	buf    []rune // The line being edited
+	cursor int    // The position of the cursor in buf
+}
+
+// NewLineEditor is a helper function to create a new LineEditor
+func NewLineEditor(in io.Reader, out io.Writer, prompt string) *LineEditor {
+	return &LineEditor{in: bufio.NewReader(in), out: out, prompt: prompt}
+}
+
+// ReadLine reads a single line. It returns io.EOF when Ctrl-D is pressed on an empty line
+func (e *LineEditor) ReadLine() (string, error) {
+	e.buf = e.buf[:0]
+	e.cursor = 0
+	historyIndex := len(e.History)
+	saved := ""
+	e.refresh()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case keyEnter, keyLineFeed:
+			fmt.Fprint(e.out, "\n")
+			return string(e.buf), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+			e.deleteRune()
+		case keyCtrlA:
+			e.cursor = 0
+		case keyCtrlE:
+			e.cursor = len(e.buf)
+		case keyCtrlB:
+			e.moveLeft()
+		case keyCtrlF:
+			e.moveRight()
+		case keyBackspace, keyDelete:
+			if e.cursor > 0 {
+				e.cursor--
+				e.deleteRune()
+			}
+		case keyCtrlK:
+			e.buf = e.buf[:e.cursor]
+		case keyCtrlU:
+			e.buf = append(e.buf[:0], e.buf[e.cursor:]...)
+			e.cursor = 0
+		case keyCtrlW:
+			e.deleteWord()
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyCtrlP:
+			historyIndex, saved = e.recall(historyIndex, historyIndex-1, saved)
+		case keyCtrlN:
+			historyIndex, saved = e.recall(historyIndex, historyIndex+1, saved)
+		case keyCtrlR:
+			if err := e.search(); err != nil {
+				return "", err
+			}
+		case keyTab:
+			e.complete()
+		case keyEscape:
+			seq, err := e.readEscape()
+			if err != nil {
+				return "", err
+			}
+			switch seq {
+			case "[A":
+				historyIndex, saved = e.recall(historyIndex, historyIndex-1, saved)
+			case "[B":
+				historyIndex, saved = e.recall(historyIndex, historyIndex+1, saved)
+			case "[C":
+				e.moveRight()
+			case "[D":
+				e.moveLeft()
+			case "[H", "[1~", "OH":
+				e.cursor = 0
+			case "[F", "[4~", "OF":
+				e.cursor = len(e.buf)
+			case "[3~":
+				e.deleteRune()
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.insert(r)
+			}
+		}
+		e.refresh()
+	}
+}
+
+// refresh redraws the prompt and the highlighted line and puts the cursor back in place
+func (e *LineEditor) refresh() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K\r", e.prompt, highlight(string(e.buf)))
+	if col := len([]rune(e.prompt)) + e.cursor; col > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dC", col)
+	}
+}
+
+// insert is a helper function that inserts r at the cursor
+func (e *LineEditor) insert(r rune) {
+	e.buf = append(e.buf, 0)
+	copy(e.buf[e.cursor+1:], e.buf[e.cursor:])
+	e.buf[e.cursor] = r
+	e.cursor++
+}
+
+// deleteRune is a helper function that deletes the rune under the cursor
+func (e *LineEditor) deleteRune() {
+	if e.cursor < len(e.buf) {
+		e.buf = append(e.buf[:e.cursor], e.buf[e.cursor+1:]...)
+	}
+}
+
+// deleteWord is a helper function that deletes the word before the cursor and the spaces after it
+func (e *LineEditor) deleteWord() {
+	start := e.cursor
+	for start > 0 && e.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+	e.buf = append(e.buf[:start], e.buf[e.cursor:]...)
+	e.cursor = start
+}
+
+func (e *LineEditor) moveLeft() {
+	if e.cursor > 0 {
+		e.cursor--
+	}
+}
+
+func (e *LineEditor) moveRight() {
+	if e.cursor < len(e.buf) {
+		e.cursor++
+	}
+}
+
+// recall replaces the line with the history entry at index to
+// The line being edited before moving into the history is kept in saved so it can be brought back
+func (e *LineEditor) recall(from, to int, saved string) (int, string) {
+	if to < 0 || to > len(e.History) {
+		return from, saved
+	}
+	if from == len(e.History) {
+		saved = string(e.buf)
+	}
+	if to == len(e.History) {
+		e.buf = []rune(saved)
+	} else {
+		e.buf = []rune(e.History[to])
+	}
+	e.cursor = len(e.buf)
+	return to, saved
+}
+
+// search implements Ctrl-R: an incremental search backwards through the history
+// Enter or any movement key accepts the match, Ctrl-G cancels the search
+// Enter and escape sequences such as the arrow keys are then handled as usual by ReadLine
+func (e *LineEditor) search() error {
+	original := string(e.buf)
+	query := []rune{}
+	index := len(e.History)
+	match := ""
+	for {
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), match)
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		switch {
+		case r == keyCtrlR:
+			// Look for an older match of the same query
+			index = e.findHistory(string(query), index-1)
+		case r == keyCtrlG || r == keyCtrlC:
+			e.buf = []rune(original)
+			e.cursor = len(e.buf)
+			return nil
+		case r == keyBackspace || r == keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+			index = e.findHistory(string(query), len(e.History)-1)
+		case unicode.IsPrint(r):
+			query = append(query, r)
+			index = e.findHistory(string(query), index)
+		default:
+			// Any other key accepts the current match
+			if index >= 0 && index < len(e.History) {
+				e.buf = []rune(e.History[index])
+			}
+			e.cursor = len(e.buf)
+			if r == keyEnter || r == keyLineFeed || r == keyEscape {
+				// Leave the key to ReadLine, so an escape sequence is not inserted as text
+				e.in.UnreadRune()
+			}
+			return nil
+		}
+		match = ""
+		if index >= 0 && index < len(e.History) {
+			match = e.History[index]
+		}
+	}
+}
+
+// findHistory is a helper function that returns the index of the newest entry at or before start that contains query
+// It returns -1 if there is no such entry
+func (e *LineEditor) findHistory(query string, start int) int {
+	if start >= len(e.History) {
+		start = len(e.History) - 1
+	}
+	for i := start; i >= 0; i-- {
+		if strings.Contains(e.History[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// complete implements tab completion of the word before the cursor
+// A single candidate is inserted, several candidates are completed to their common prefix and listed
+func (e *LineEditor) complete() {
+	if e.Complete == nil {
+		return
+	}
+	start := e.cursor
+	for start > 0 && isWordRune(e.buf[start-1]) {
+		start--
+	}
+	prefix := string(e.buf[start:e.cursor])
+	candidates := e.Complete(prefix)
+	if len(candidates) == 0 {
+		return
+	}
+	sort.Strings(candidates)
+	common := candidates[0]
+	for _, c := range candidates[1:] {
+		common = commonPrefix(common, c)
+	}
+	if len(candidates) == 1 {
+		common += " "
+	}
+	if common != prefix {
+		for _, r := range []rune(common)[len([]rune(prefix)):] {
+			e.insert(r)
+		}
+		return
+	}
+	fmt.Fprintf(e.out, "\n%s\n", strings.Join(candidates, "  "))
+}
+
+// isWordRune is a helper function that checks if r can be part of an identifier
+func isWordRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_'
+}
+
+// commonPrefix is a helper function that returns the longest common prefix of a and b
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+// readEscape reads the rest of an escape sequence such as "[A" for the up arrow
+func (e *LineEditor) readEscape() (string, error) {
+	var seq []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		seq = append(seq, r)
+		// A sequence ends with a letter or '~', except for the '[' or 'O' that introduces it
+		if len(seq) > 1 && (unicode.IsLetter(r) || r == '~') {
+			return string(seq), nil
+		}
+		if len(seq) == 1 && r != '[' && r != 'O' {
+			return string(seq), nil
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"os"
+	"strings"
+	"time"
+)
+
+const PROMPT = ">> "
+
+// Session holds the state of a single REPL session
+// Every line that is not a meta-command is parsed and recorded in the history. There is no evaluator,
+// so nothing runs: a line prints the statements it parses to, and its let statements are recorded as
+// the bindings of the session, by their source rather than by a value
+type Session struct {
+	out      io.Writer
+	history  []string                     // The source lines entered so far
+	bindings map[string]*ast.LetStatement // The let bindings made so far
+	names    []string                     // The binding names in the order they were first bound
+	timing   bool                         // Print how long each input took to process
+	quit     bool                         // Set by the :quit command
+}
+
+// NewSession is a helper function to create a new Session that writes to out
+func NewSession(out io.Writer) *Session {
+	return &Session{out: out, bindings: map[string]*ast.LetStatement{}}
+}
+
+// Start reads lines from in until EOF and writes the results to out
+// When in is a terminal the lines are read with a LineEditor and the history is kept in the user's home directory
+func Start(in io.Reader, out io.Writer) {
+	s := NewSession(out)
+	if f, ok := in.(*os.File); ok {
+		if restore, err := makeRaw(f.Fd()); err == nil {
+			defer restore()
+			s.edit(in)
+			return
+		}
+	}
+	scanner := bufio.NewScanner(in)
+	for !s.quit {
+		fmt.Fprint(out, PROMPT)
+		if !scanner.Scan() {
+			return
+		}
+		s.Execute(scanner.Text())
+	}
+}
+
+// edit reads lines with a LineEditor until EOF or :quit
+func (s *Session) edit(in io.Reader) {
+	e := NewLineEditor(in, s.out, PROMPT)
+	e.Complete = s.Complete
+	path := historyPath()
+	if path != "" {
+		e.History = loadHistory(path)
+	}
+	warned := false // Whether a failure to save the history was reported already
+	for !s.quit {
+		line, err := e.ReadLine()
+		if err == errInterrupted {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if strings.TrimSpace(line) != "" {
+			e.History = append(e.History, line)
+			if path != "" {
+				if err := appendHistory(path, line); err != nil && !warned {
+					fmt.Fprintf(s.out, "warning: cannot save the history: %s\n", err)
+					warned = true
+				}
+			}
+		}
+		s.Execute(line)
+	}
+}
+
+// Complete returns the keywords and bound names that start with prefix
+func (s *Session) Complete(prefix string) []string {
+	var candidates []string
+	for _, word := range token.Keywords() {
+		if strings.HasPrefix(word, prefix) {
+			candidates = append(candidates, word)
+		}
+	}
+	for _, name := range s.names {
+		if strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// Execute runs a single line of input. Lines starting with ':' are meta-commands
+func (s *Session) Execute(line string) {
+	if strings.HasPrefix(strings.TrimSpace(line), ":") {
+		s.runCommand(strings.TrimSpace(line))
+		return
+	}
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	s.history = append(s.history, line)
+	s.input(line)
+}
+
+// Out returns the writer the session prints to
+func (s *Session) Out() io.Writer {
+	return s.out
+}
+
+// History returns the source lines entered so far
+func (s *Session) History() []string {
+	return s.history
+}
+
+// Bindings returns the names bound in the session in the order they were first bound
+func (s *Session) Bindings() []*ast.LetStatement {
+	bindings := make([]*ast.LetStatement, 0, len(s.names))
+	for _, name := range s.names {
+		bindings = append(bindings, s.bindings[name])
+	}
+	return bindings
+}
+
+// input parses the input, records its bindings and prints the parsed statements
+func (s *Session) input(input string) {
+	start := time.Now()
+	program, errors := s.parse(input)
+	elapsed := time.Since(start)
+	if len(errors) != 0 {
+		printParserErrors(s.out, errors)
+		return
+	}
+	for _, stmt := range program.Statements {
+		fmt.Fprintln(s.out, stmt.String())
+	}
+	if s.timing {
+		fmt.Fprintf(s.out, "(%s)\n", elapsed)
+	}
+}
+
+// parse parses the input and records every let statement as a binding
+func (s *Session) parse(input string) (*ast.Program, []string) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, p.Errors()
+	}
+	for _, stmt := range program.Statements {
+		if ls, ok := stmt.(*ast.LetStatement); ok && ls.Name != nil {
+			s.bind(ls)
+		}
+	}
+	return program, nil
+}
+
+// bind records the let statement as the current binding of its name
+func (s *Session) bind(ls *ast.LetStatement) {
+	if _, ok := s.bindings[ls.Name.Value]; !ok {
+		s.names = append(s.names, ls.Name.Value)
+	}
+	s.bindings[ls.Name.Value] = ls
+}
+
+// printParserErrors is a helper function that prints all the parser errors
+func printParserErrors(out io.Writer, errors []string) {
+	fmt.Fprintln(out, "parser errors:")
+	for _, msg := range errors {
+		fmt.Fprintf(out, "\t%s\n", msg)
+	}
+}
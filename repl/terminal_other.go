@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package repl
+
+import "errors"
+
+// makeRaw is only implemented on linux. Everywhere else the REPL falls back to reading plain lines
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
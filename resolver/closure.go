@@ -0,0 +1,79 @@
+package resolver
+
+import "monkey/ast"
+
+// Closure describes the free variables of a function literal
+// A symbol declared outside every function is a global, which a function refers to but does not
+// capture. Captures holds the symbols of enclosing functions, including those that only a nested
+// function refers to, since the nested function is created from the environment of this one
+type Closure struct {
+	Func     *ast.FunctionLiteral
+	Depth    int        // The number of function literals around Func, 0 for a function at the top level
+	Captures []*Capture // In the order of their first use
+	Globals  []*Symbol  // The globals referred to, in the order of their first use
+}
+
+// Capture is a symbol of an enclosing function that a function literal refers to
+type Capture struct {
+	Symbol     *Symbol
+	First      *ast.Identifier // The first use of the symbol inside the function
+	Depth      int             // How many functions out the symbol is declared, 1 for the immediately enclosing function
+	Reassigned bool            // Whether the symbol is let more than once, so the captured value may not be the last one
+}
+
+// Closure returns the closure of fn, or nil if fn is not part of the resolved program
+func (info *Info) Closure(fn *ast.FunctionLiteral) *Closure {
+	return info.Closures[fn]
+}
+
+// closures is a helper function that builds the closures of every function literal of program
+func (r *resolver) closures(program *ast.Program) {
+	var stack []*Closure
+	seen := map[*Closure]map[*Symbol]bool{}
+	var visit func(ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case nil:
+			return false
+		case *ast.FunctionLiteral:
+			c := &Closure{Func: n, Depth: len(stack)}
+			r.info.Closures[n] = c
+			seen[c] = map[*Symbol]bool{}
+			stack = append(stack, c)
+			ast.Inspect(n.Body, visit)
+			stack = stack[:len(stack)-1]
+			return false
+		case *ast.Identifier:
+			sym := r.info.Uses[n]
+			if sym == nil {
+				return false
+			}
+			declaredIn := functionDepth(sym.Scope)
+			for _, c := range stack {
+				// c is at function depth c.Depth+1 and captures what is declared outside of it
+				if declaredIn > c.Depth || seen[c][sym] {
+					continue
+				}
+				seen[c][sym] = true
+				if declaredIn == 0 {
+					c.Globals = append(c.Globals, sym)
+					continue
+				}
+				c.Captures = append(c.Captures, &Capture{Symbol: sym, First: n, Depth: c.Depth + 1 - declaredIn, Reassigned: sym.Reassigned()})
+			}
+		}
+		return true
+	}
+	ast.Inspect(program, visit)
+}
+
+// functionDepth is a helper function that returns the number of function scopes that are or enclose s
+func functionDepth(s *Scope) int {
+	depth := 0
+	for ; s != nil; s = s.Parent {
+		if s.Kind == FunctionScope {
+			depth++
+		}
+	}
+	return depth
+}
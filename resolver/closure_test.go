@@ -0,0 +1,127 @@
+package resolver
+
+import (
+	"fmt"
+	"monkey/ast"
+	"strings"
+	"testing"
+)
+
+// describe prints the closures of the function literals of src in source order, one per line:
+// the parameters and the depth of the function, then its captures as name/depth, with a * for a
+// reassigned symbol, and its globals
+func describe(t *testing.T, src string) string {
+	program := parse(t, src)
+	info := Resolve(program)
+	var b strings.Builder
+	ast.Inspect(program, func(n ast.Node) bool {
+		fn, ok := n.(*ast.FunctionLiteral)
+		if !ok {
+			return n != nil
+		}
+		c := info.Closure(fn)
+		var params []string
+		for _, p := range fn.Parameters {
+			params = append(params, p.Value)
+		}
+		fmt.Fprintf(&b, "fn(%s) %d:", strings.Join(params, ", "), c.Depth)
+		for _, capture := range c.Captures {
+			fmt.Fprintf(&b, " %s/%d", capture.Symbol.Name, capture.Depth)
+			if capture.Reassigned {
+				b.WriteString("*")
+			}
+			if capture.First.Value != capture.Symbol.Name {
+				t.Errorf("%q: the first use of %s is %s", src, capture.Symbol.Name, capture.First.Value)
+			}
+		}
+		if len(c.Globals) > 0 {
+			b.WriteString(" globals")
+			for _, g := range c.Globals {
+				fmt.Fprintf(&b, " %s", g.Name)
+			}
+		}
+		b.WriteString("\n")
+		return true
+	})
+	return b.String()
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{
+			`let f = fn(x) { x };`,
+			`
+fn(x) 0:
+`,
+		},
+		{
+			`let g = 1; let f = fn() { g + h }; let h = 2;`,
+			`
+fn() 0: globals g h
+`,
+		},
+		{
+			`let adder = fn(x) { fn(y) { x + y } };`,
+			`
+fn(x) 0:
+fn(y) 1: x/1
+`,
+		},
+		{
+			// The middle function captures x for the inner one, which is two functions in
+			`let f = fn(x) { fn() { fn() { x } } };`,
+			`
+fn(x) 0:
+fn() 1: x/1
+fn() 2: x/2
+`,
+		},
+		{
+			`let f = fn() { let a = 1; let a = 2; fn() { a } };`,
+			`
+fn() 0:
+fn() 1: a/1*
+`,
+		},
+		{
+			// Captures and globals are in the order of their first use and listed once
+			`let g = 1; let f = fn(a, b) { fn() { b + g + a + b + g } };`,
+			`
+fn(a, b) 0: globals g
+fn() 1: b/1 a/1 globals g
+`,
+		},
+		{
+			// A let in an if block of the enclosing function is captured too
+			`let f = fn(c) { if (c) { let d = 1; fn() { d } } };`,
+			`
+fn(c) 0:
+fn() 1: d/1
+`,
+		},
+		{
+			// A parameter of the function itself is not captured
+			`let f = fn(x) { fn(x) { x } };`,
+			`
+fn(x) 0:
+fn(x) 1:
+`,
+		},
+	}
+	for _, tt := range tests {
+		if got := describe(t, tt.src); got != strings.TrimPrefix(tt.want, "\n") {
+			t.Errorf("%s\ngot\n%swant%s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestClosureOutsideProgram(t *testing.T) {
+	info := Resolve(parse(t, "let f = fn() { 1 };"))
+	other := parse(t, "fn() { 2 };").Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if c := info.Closure(other); c != nil {
+		t.Errorf("Closure of a function of another program = %+v, want nil", c)
+	}
+}
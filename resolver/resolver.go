@@ -0,0 +1,297 @@
+// Package resolver binds the identifiers of a Monkey program to their declarations
+//
+// The program, every function literal and every block of an if expression open a lexical scope.
+// A let statement declares its name from the next statement on, except that a function literal can
+// refer to the let it is the value of, to call itself. A use inside a function literal may also
+// refer to a let of an enclosing scope that comes after the function, since the function cannot run
+// before that let has been evaluated. Letting a name again in the same scope rebinds it
+//
+// A macro literal opens a function scope for its parameters. The quoted code of its body belongs
+// to where the macro is expanded, so only the arguments of its unquote calls are resolved
+package resolver
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+	"sort"
+)
+
+// Info is the symbol table of a resolved program
+type Info struct {
+	Scope    *Scope                            // The program scope
+	Scopes   map[ast.Node]*Scope               // The scope opened by every Program, FunctionLiteral, MacroLiteral and if BlockStatement; a function body maps to its function scope
+	Defs     map[*ast.Identifier]*Symbol       // Every declaring identifier to its symbol
+	Uses     map[*ast.Identifier]*Symbol       // Every resolved use to its symbol
+	Problems []*Problem                        // The problems in source order
+	Closures map[*ast.FunctionLiteral]*Closure // The free variables of every function literal
+}
+
+// SymbolOf returns the symbol that id declares or refers to, or nil if it is undefined
+func (info *Info) SymbolOf(id *ast.Identifier) *Symbol {
+	if sym := info.Defs[id]; sym != nil {
+		return sym
+	}
+	return info.Uses[id]
+}
+
+// ScopeAt returns the innermost scope that contains the position
+func (info *Info) ScopeAt(pos token.Position) *Scope {
+	return info.Scope.Innermost(pos.Offset)
+}
+
+// Errors returns the problems that are errors, leaving out the warnings
+func (info *Info) Errors() []*Problem {
+	var errs []*Problem
+	for _, p := range info.Problems {
+		if p.IsError() {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// ProblemKind is the kind of a problem found by the resolver
+type ProblemKind int
+
+const (
+	Undefined          ProblemKind = iota // A use of a name that is not declared
+	DuplicateParameter                    // A parameter with the name of an earlier parameter of the same function
+	Shadowing                             // A declaration that hides a symbol of an enclosing scope, a warning
+)
+
+func (k ProblemKind) String() string {
+	switch k {
+	case Undefined:
+		return "undefined"
+	case DuplicateParameter:
+		return "duplicate parameter"
+	case Shadowing:
+		return "shadowing"
+	}
+	return "unknown"
+}
+
+// Problem is a problem with the names of a program
+type Problem struct {
+	Kind    ProblemKind
+	Ident   *ast.Identifier // The offending identifier
+	Related *Symbol         // The earlier parameter or the shadowed symbol, nil for an undefined name
+	Msg     string
+}
+
+// Pos returns the position of the offending identifier
+func (p *Problem) Pos() token.Position { return p.Ident.Pos() }
+
+// End returns the position just after the offending identifier
+func (p *Problem) End() token.Position { return p.Ident.End() }
+
+// IsError reports whether the problem is an error rather than a warning
+func (p *Problem) IsError() bool {
+	return p.Kind != Shadowing
+}
+
+// Error formats the problem as "line:column: message"
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Pos(), p.Msg)
+}
+
+// Resolve builds the scopes of program and binds its identifiers
+// A program with parse errors can be resolved, the parts the parser dropped are skipped
+func Resolve(program *ast.Program) *Info {
+	r := &resolver{info: &Info{
+		Scopes:   map[ast.Node]*Scope{},
+		Defs:     map[*ast.Identifier]*Symbol{},
+		Uses:     map[*ast.Identifier]*Symbol{},
+		Closures: map[*ast.FunctionLiteral]*Closure{},
+	}}
+	r.scope = newScope(ProgramScope, program, nil)
+	r.info.Scope = r.scope
+	r.info.Scopes[program] = r.scope
+	r.statements(program.Statements)
+	r.resolvePending()
+	r.closures(program)
+	for _, sym := range r.symbols {
+		sort.SliceStable(sym.Uses, func(i, j int) bool { return sym.Uses[i].Pos().Offset < sym.Uses[j].Pos().Offset })
+	}
+	sort.SliceStable(r.info.Problems, func(i, j int) bool {
+		return r.info.Problems[i].Pos().Offset < r.info.Problems[j].Pos().Offset
+	})
+	return r.info
+}
+
+// resolver holds the state of Resolve
+type resolver struct {
+	info    *Info
+	scope   *Scope    // The current scope
+	symbols []*Symbol // Every symbol in declaration order
+	pending []pendingUse
+}
+
+// pendingUse is a use inside a function that no declaration before it matches
+// It is resolved once the enclosing scopes are complete
+type pendingUse struct {
+	id    *ast.Identifier
+	scope *Scope
+}
+
+func (r *resolver) open(kind ScopeKind, node ast.Node) {
+	r.scope = newScope(kind, node, r.scope)
+	r.info.Scopes[node] = r.scope
+}
+
+func (r *resolver) close() {
+	r.scope = r.scope.Parent
+}
+
+func (r *resolver) problem(kind ProblemKind, id *ast.Identifier, related *Symbol, format string, args ...interface{}) {
+	r.info.Problems = append(r.info.Problems, &Problem{Kind: kind, Ident: id, Related: related, Msg: fmt.Sprintf(format, args...)})
+}
+
+// declare binds id in the current scope
+func (r *resolver) declare(kind SymbolKind, id *ast.Identifier) {
+	if sym := r.scope.Lookup(id.Value); sym != nil {
+		if kind == ParamSymbol {
+			r.problem(DuplicateParameter, id, sym, "duplicate parameter %s", id.Value)
+		} else {
+			sym.Decls = append(sym.Decls, id)
+		}
+		r.info.Defs[id] = sym
+		return
+	}
+	if outer := r.scope.Parent.LookupParent(id.Value); outer != nil {
+		r.problem(Shadowing, id, outer, "%s shadows the %s declared at %s", id.Value, outer.Kind, outer.Decl.Pos())
+	}
+	sym := r.scope.declare(kind, id)
+	r.symbols = append(r.symbols, sym)
+	r.info.Defs[id] = sym
+}
+
+// use binds id to the symbol it refers to
+func (r *resolver) use(id *ast.Identifier) {
+	if sym := r.scope.LookupParent(id.Value); sym != nil {
+		r.bind(id, sym)
+		return
+	}
+	if r.scope.Function() == nil {
+		r.problem(Undefined, id, nil, "undefined: %s", id.Value)
+		return
+	}
+	r.pending = append(r.pending, pendingUse{id: id, scope: r.scope})
+}
+
+func (r *resolver) bind(id *ast.Identifier, sym *Symbol) {
+	sym.Uses = append(sym.Uses, id)
+	r.info.Uses[id] = sym
+}
+
+// resolvePending is a helper function that binds the uses inside functions to the lets of the
+// enclosing scopes that come after the function
+// The function itself is executed in order, so its own scopes are left out
+func (r *resolver) resolvePending() {
+	for _, p := range r.pending {
+		if sym := p.scope.Function().Parent.LookupParent(p.id.Value); sym != nil {
+			r.bind(p.id, sym)
+			continue
+		}
+		r.problem(Undefined, p.id, nil, "undefined: %s", p.id.Value)
+	}
+}
+
+func (r *resolver) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		r.statement(stmt)
+	}
+}
+
+func (r *resolver) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		if _, ok := s.Value.(*ast.FunctionLiteral); ok && s.Name != nil {
+			r.declare(LetSymbol, s.Name)
+			r.expression(s.Value)
+			return
+		}
+		r.expression(s.Value)
+		if s.Name != nil {
+			r.declare(LetSymbol, s.Name)
+		}
+	case *ast.ReturnStatement:
+		r.expression(s.ReturnValue)
+	case *ast.ExpressionStatement:
+		r.expression(s.Expression)
+	case *ast.BlockStatement:
+		r.block(s)
+	}
+}
+
+func (r *resolver) block(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+	r.open(BlockScope, block)
+	r.statements(block.Statements)
+	r.close()
+}
+
+func (r *resolver) expression(expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		if e != nil {
+			r.use(e)
+		}
+	case *ast.PrefixExpression:
+		r.expression(e.Right)
+	case *ast.InfixExpression:
+		r.expression(e.Left)
+		r.expression(e.Right)
+	case *ast.IfExpression:
+		r.expression(e.Condition)
+		r.block(e.Consequence)
+		r.block(e.Alternative)
+	case *ast.FunctionLiteral:
+		r.open(FunctionScope, e)
+		for _, p := range e.Parameters {
+			if p != nil {
+				r.declare(ParamSymbol, p)
+			}
+		}
+		if e.Body != nil {
+			r.info.Scopes[e.Body] = r.scope
+			r.statements(e.Body.Statements)
+		}
+		r.close()
+	case *ast.MacroLiteral:
+		r.open(FunctionScope, e)
+		for _, p := range e.Parameters {
+			if p != nil {
+				r.declare(ParamSymbol, p)
+			}
+		}
+		if e.Body != nil {
+			r.info.Scopes[e.Body] = r.scope
+			r.unquoted(e.Body)
+		}
+		r.close()
+	case *ast.CallExpression:
+		r.expression(e.Function)
+		for _, arg := range e.Arguments {
+			r.expression(arg)
+		}
+	}
+}
+
+// unquoted resolves the arguments of the unquote calls in the body of a macro
+func (r *resolver) unquoted(body *ast.BlockStatement) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpression); ok {
+			if id, ok := call.Function.(*ast.Identifier); ok && id.Value == "unquote" {
+				for _, arg := range call.Arguments {
+					r.expression(arg)
+				}
+				return false
+			}
+		}
+		return n != nil
+	})
+}
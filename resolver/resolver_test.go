@@ -0,0 +1,196 @@
+package resolver
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/parser"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	program, err := parser.ParseFile("", src, 0)
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	return program
+}
+
+// bindings lists the identifiers of program in source order, as "name@pos let" or "name@pos parameter"
+// for a declaration, "name@pos -> decl" for a use and "name@pos ?" for an identifier that is neither
+func bindings(program *ast.Program, info *Info) []string {
+	var ids []*ast.Identifier
+	ast.Inspect(program, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Identifier); ok {
+			ids = append(ids, id)
+		}
+		return n != nil
+	})
+	sort.SliceStable(ids, func(i, j int) bool { return ids[i].Pos().Offset < ids[j].Pos().Offset })
+	var out []string
+	for _, id := range ids {
+		s := fmt.Sprintf("%s@%s", id.Value, id.Pos())
+		if sym := info.Defs[id]; sym != nil {
+			s += " " + sym.Kind.String()
+		} else if sym := info.Uses[id]; sym != nil {
+			s += " -> " + sym.Decl.Pos().String()
+		} else {
+			s += " ?"
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestBindings(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			"let",
+			"let a = 1; a;",
+			[]string{"a@1:5 let", "a@1:12 -> 1:5"},
+		},
+		{
+			"parameters",
+			"let f = fn(x, y) { x + y };",
+			[]string{"f@1:5 let", "x@1:12 parameter", "y@1:15 parameter", "x@1:20 -> 1:12", "y@1:24 -> 1:15"},
+		},
+		{
+			"a let is not visible in its own value",
+			"let a = a;",
+			[]string{"a@1:5 let", "a@1:9 ?"},
+		},
+		{
+			"a function can call itself",
+			"let f = fn(n) { f(n) };",
+			[]string{"f@1:5 let", "n@1:12 parameter", "f@1:17 -> 1:5", "n@1:19 -> 1:12"},
+		},
+		{
+			"a function can use a later let",
+			"let f = fn() { g() }; let g = fn() { 1 };",
+			[]string{"f@1:5 let", "g@1:16 -> 1:27", "g@1:27 let"},
+		},
+		{
+			"letting a name again rebinds it",
+			"let a = 1; let a = a + 1; a;",
+			[]string{"a@1:5 let", "a@1:16 let", "a@1:20 -> 1:5", "a@1:27 -> 1:5"},
+		},
+		{
+			"an if block is a scope",
+			"if (true) { let b = 1; b }; b;",
+			[]string{"b@1:17 let", "b@1:24 -> 1:17", "b@1:29 ?"},
+		},
+		{
+			"a parameter hides a global",
+			"let x = 1; let f = fn(x) { x };",
+			[]string{"x@1:5 let", "f@1:16 let", "x@1:23 parameter", "x@1:28 -> 1:23"},
+		},
+		{
+			"only the unquoted code of a macro is resolved",
+			"let m = macro(x) { quote(unquote(x) + y) };",
+			[]string{"m@1:5 let", "x@1:15 parameter", "quote@1:20 ?", "unquote@1:26 ?", "x@1:34 -> 1:15", "y@1:39 ?"},
+		},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.src)
+		got := bindings(program, Resolve(program))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %q\ngot  %q\nwant %q", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestProblems(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string // "pos kind: message", with the position of the related symbol if any
+	}{
+		{"let a = 1; a;", nil},
+		{"b;", []string{"1:1 undefined: undefined: b"}},
+		{"let f = fn() { g };", []string{"1:16 undefined: undefined: g"}},
+		{"let f = fn(a, a) { a };", []string{"1:15 duplicate parameter: duplicate parameter a (1:12)"}},
+		{
+			"let x = 1; let f = fn(x) { if (x) { let x = 2; x } };",
+			[]string{
+				"1:23 shadowing: x shadows the let declared at 1:5 (1:5)",
+				"1:41 shadowing: x shadows the parameter declared at 1:23 (1:23)",
+			},
+		},
+		// The body of a function is the scope of its parameters, so a let there rebinds them
+		{"let f = fn(x) { let x = 2; x };", nil},
+		{"if (true) { let y = 1; }; let y = 2;", nil},
+		// The quoted code of a macro is not resolved, so y is not undefined
+		{"let m = macro(x) { quote(unquote(x) + y) };", nil},
+		{"let m = macro(x) { quote(unquote(z)) };", []string{"1:34 undefined: undefined: z"}},
+	}
+	for _, tt := range tests {
+		info := Resolve(parse(t, tt.src))
+		var got []string
+		for _, p := range info.Problems {
+			s := fmt.Sprintf("%s %s: %s", p.Pos(), p.Kind, p.Msg)
+			if p.Related != nil {
+				s += fmt.Sprintf(" (%s)", p.Related.Decl.Pos())
+			}
+			got = append(got, s)
+			if p.IsError() != (p.Kind != Shadowing) {
+				t.Errorf("%q: IsError of %s is %v", tt.src, p.Kind, p.IsError())
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q:\ngot  %q\nwant %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// tree prints the scopes under s, one per line and indented by their depth, with their symbols
+func tree(s *Scope, depth int, b *strings.Builder) {
+	fmt.Fprintf(b, "%s%s", strings.Repeat("  ", depth), s.Kind)
+	for _, sym := range s.Symbols {
+		fmt.Fprintf(b, " %s", sym.Name)
+	}
+	b.WriteString("\n")
+	for _, child := range s.Children {
+		tree(child, depth+1, b)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	src := `let a = 1;
+let f = fn(x) {
+	if (x) { let b = 2; b } else { x }
+};`
+	program := parse(t, src)
+	info := Resolve(program)
+	var b strings.Builder
+	tree(info.Scope, 0, &b)
+	want := `program a f
+  function x
+    block b
+    block
+`
+	if b.String() != want {
+		t.Errorf("scopes:\n%s\nwant\n%s", b.String(), want)
+	}
+
+	// The offset of b in "let b = 2" is in the block of the consequence
+	offset := strings.Index(src, "b = 2")
+	inner := info.Scope.Innermost(offset)
+	if inner == nil || inner.Kind != BlockScope || inner.Lookup("b") == nil {
+		t.Fatalf("Innermost(%d) = %+v, want the block that declares b", offset, inner)
+	}
+	if inner.LookupParent("a") != info.Scope.Lookup("a") || inner.LookupParent("c") != nil {
+		t.Errorf("LookupParent does not find a in the program scope")
+	}
+	if fn := inner.Function(); fn == nil || fn.Lookup("x") == nil {
+		t.Errorf("Function() = %+v, want the scope of f", fn)
+	}
+	if info.Scope.Innermost(offset).Function().Function() != info.Scope.Innermost(offset).Function() {
+		t.Errorf("Function of a function scope is not the scope itself")
+	}
+}
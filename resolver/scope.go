@@ -0,0 +1,129 @@
+package resolver
+
+import (
+	"monkey/ast"
+	"monkey/token"
+)
+
+// ScopeKind is the kind of a lexical scope
+type ScopeKind int
+
+const (
+	ProgramScope  ScopeKind = iota // The top level of a program
+	FunctionScope                  // The parameters and the body of a function literal
+	BlockScope                     // The consequence or the alternative of an if expression
+)
+
+func (k ScopeKind) String() string {
+	switch k {
+	case ProgramScope:
+		return "program"
+	case FunctionScope:
+		return "function"
+	case BlockScope:
+		return "block"
+	}
+	return "unknown"
+}
+
+// Scope is a lexical scope and the symbols declared in it
+type Scope struct {
+	Kind     ScopeKind
+	Node     ast.Node // The *ast.Program, *ast.FunctionLiteral, *ast.MacroLiteral or *ast.BlockStatement that opens the scope
+	Parent   *Scope   // nil for the program scope
+	Children []*Scope // The nested scopes in source order
+	Symbols  []*Symbol
+	names    map[string]*Symbol
+}
+
+func newScope(kind ScopeKind, node ast.Node, parent *Scope) *Scope {
+	s := &Scope{Kind: kind, Node: node, Parent: parent, names: map[string]*Symbol{}}
+	if parent != nil {
+		parent.Children = append(parent.Children, s)
+	}
+	return s
+}
+
+// Pos returns the position of the first character of the scope
+func (s *Scope) Pos() token.Position { return s.Node.Pos() }
+
+// End returns the position just after the last character of the scope
+func (s *Scope) End() token.Position { return s.Node.End() }
+
+// Lookup returns the symbol declared with name in s itself, or nil
+func (s *Scope) Lookup(name string) *Symbol {
+	return s.names[name]
+}
+
+// LookupParent returns the symbol declared with name in s or the nearest enclosing scope, or nil
+// It looks at every declaration of the scopes, whether it comes before or after a given use
+func (s *Scope) LookupParent(name string) *Symbol {
+	for ; s != nil; s = s.Parent {
+		if sym := s.names[name]; sym != nil {
+			return sym
+		}
+	}
+	return nil
+}
+
+// Function returns the innermost function scope that is or encloses s, or nil at the top level
+func (s *Scope) Function() *Scope {
+	for ; s != nil; s = s.Parent {
+		if s.Kind == FunctionScope {
+			return s
+		}
+	}
+	return nil
+}
+
+// Innermost returns the innermost scope of s and its children that contains the offset, or nil
+func (s *Scope) Innermost(offset int) *Scope {
+	if s.Kind != ProgramScope && (offset < s.Pos().Offset || offset >= s.End().Offset) {
+		return nil
+	}
+	for _, child := range s.Children {
+		if inner := child.Innermost(offset); inner != nil {
+			return inner
+		}
+	}
+	return s
+}
+
+// declare is a helper function that adds a new symbol to s
+func (s *Scope) declare(kind SymbolKind, id *ast.Identifier) *Symbol {
+	sym := &Symbol{Name: id.Value, Kind: kind, Decl: id, Decls: []*ast.Identifier{id}, Scope: s}
+	s.Symbols = append(s.Symbols, sym)
+	s.names[id.Value] = sym
+	return sym
+}
+
+// SymbolKind is the kind of declaration of a symbol
+type SymbolKind int
+
+const (
+	LetSymbol   SymbolKind = iota // Declared by a let statement
+	ParamSymbol                   // Declared as a parameter of a function literal
+)
+
+func (k SymbolKind) String() string {
+	if k == ParamSymbol {
+		return "parameter"
+	}
+	return "let"
+}
+
+// Symbol is a name declared in a scope
+// Letting the same name again in the same scope rebinds the symbol, so a symbol may have several declarations
+type Symbol struct {
+	Name  string
+	Kind  SymbolKind
+	Decl  *ast.Identifier   // The first declaration
+	Decls []*ast.Identifier // Every declaration in source order, starting with Decl
+	Scope *Scope
+	Uses  []*ast.Identifier // The identifiers that refer to the symbol, in source order
+}
+
+// Reassigned reports whether the symbol is let more than once
+func (s *Symbol) Reassigned() bool {
+	return len(s.Decls) > 1
+}
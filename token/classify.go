@@ -0,0 +1,72 @@
+package token
+
+// Precedences of the infix operators, from the loosest to the tightest binding
+// An expression is parsed with a precedence and only takes operators that bind tighter than it
+const (
+	_ int = iota
+	LOWEST
+	EQUALS      // == !=
+	LESSGREATER // < >
+	SUM         // + -
+	PRODUCT     // * /
+	PREFIX      // ! + -
+	CALL        // fn()
+)
+
+// The precedence table of the tokens that can follow an expression
+var precedences = map[TokenType]int{
+	EQ:       EQUALS,
+	NOT_EQ:   EQUALS,
+	LT:       LESSGREATER,
+	GT:       LESSGREATER,
+	PLUS:     SUM,
+	MINUS:    SUM,
+	SLASH:    PRODUCT,
+	ASTERISK: PRODUCT,
+	LPAREN:   CALL,
+}
+
+// The operators, for IsOperator
+var operators = map[TokenType]bool{
+	ASSIGN:   true,
+	PLUS:     true,
+	MINUS:    true,
+	BANG:     true,
+	ASTERISK: true,
+	SLASH:    true,
+	LT:       true,
+	GT:       true,
+	EQ:       true,
+	NOT_EQ:   true,
+}
+
+// Precedence returns the precedence of tt as an infix operator
+// The '(' of a call has the precedence CALL. Tokens that cannot follow an expression have LOWEST
+func (tt TokenType) Precedence() int {
+	if p, ok := precedences[tt]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+// IsKeyword reports whether tt is the type of a keyword such as "let" or "true"
+func (tt TokenType) IsKeyword() bool {
+	for _, kw := range keywords {
+		if kw == tt {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOperator reports whether tt is the type of an operator such as "+" or "=="
+// Delimiters like "(" and ";" are not operators
+func (tt TokenType) IsOperator() bool {
+	return operators[tt]
+}
+
+// IsLiteral reports whether tt is the type of an identifier or an integer literal
+// The boolean literals are keywords
+func (tt TokenType) IsLiteral() bool {
+	return tt == IDENT || tt == INT
+}
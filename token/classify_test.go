@@ -0,0 +1,65 @@
+package token
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		tt                         TokenType
+		keyword, operator, literal bool
+		precedence                 int
+	}{
+		{LET, true, false, false, LOWEST},
+		{TRUE, true, false, false, LOWEST},
+		{MACRO, true, false, false, LOWEST},
+		{IDENT, false, false, true, LOWEST},
+		{INT, false, false, true, LOWEST},
+		{ASSIGN, false, true, false, LOWEST},
+		{BANG, false, true, false, LOWEST},
+		{EQ, false, true, false, EQUALS},
+		{NOT_EQ, false, true, false, EQUALS},
+		{LT, false, true, false, LESSGREATER},
+		{PLUS, false, true, false, SUM},
+		{MINUS, false, true, false, SUM},
+		{ASTERISK, false, true, false, PRODUCT},
+		{SLASH, false, true, false, PRODUCT},
+		{LPAREN, false, false, false, CALL},
+		{SEMICOLON, false, false, false, LOWEST},
+		{ARROW, false, false, false, LOWEST},
+		{COMMENT, false, false, false, LOWEST},
+		{EOF, false, false, false, LOWEST},
+		{ILLEGAL, false, false, false, LOWEST},
+	}
+	for _, tt := range tests {
+		if got := tt.tt.IsKeyword(); got != tt.keyword {
+			t.Errorf("%s.IsKeyword() = %t", tt.tt, got)
+		}
+		if got := tt.tt.IsOperator(); got != tt.operator {
+			t.Errorf("%s.IsOperator() = %t", tt.tt, got)
+		}
+		if got := tt.tt.IsLiteral(); got != tt.literal {
+			t.Errorf("%s.IsLiteral() = %t", tt.tt, got)
+		}
+		if got := tt.tt.Precedence(); got != tt.precedence {
+			t.Errorf("%s.Precedence() = %d, want %d", tt.tt, got, tt.precedence)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	words := Keywords()
+	if got, want := strings.Join(words, " "), "else false fn if let macro return true"; got != want {
+		t.Errorf("Keywords() = %s, want %s", got, want)
+	}
+	// Every keyword is lexed to a keyword type, and only keywords are
+	for _, word := range words {
+		if tt := LookUpIdentifier(word); !tt.IsKeyword() {
+			t.Errorf("%s is looked up as %s, which is not a keyword", word, tt)
+		}
+	}
+	if tt := LookUpIdentifier("lets"); tt != IDENT || tt.IsKeyword() {
+		t.Errorf("lets is looked up as %s", tt)
+	}
+}
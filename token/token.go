@@ -1,15 +1,48 @@
 package token
 
+import (
+	"fmt"
+	"sort"
+)
+
 type TokenType string
 
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type    TokenType `json:"type"`
+	Literal string    `json:"literal"`
+	Pos     Position  `json:"pos"` // The position of the first character of the token
+}
+
+// Position is a location in the source
+// Lines and columns start at 1, the column counts bytes
+type Position struct {
+	Offset int `json:"offset"` // The byte offset from the start of the source
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// IsValid reports whether the position has been set
+func (pos Position) IsValid() bool {
+	return pos.Line > 0
+}
+
+func (pos Position) String() string {
+	if !pos.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
+
+// End returns the position just after the last character of the token
+// Tokens never span several lines
+func (t Token) End() Position {
+	return Position{Offset: t.Pos.Offset + len(t.Literal), Line: t.Pos.Line, Column: t.Pos.Column + len(t.Literal)}
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // A line comment. The parser never sees these, the lexer keeps them aside
 
 	// Identifiers and Literals
 	IDENT = "IDENT"
@@ -24,11 +57,15 @@ const (
 	// Delimeter
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"  // Before a type annotation
+	ARROW     = "->" // Before the result type of a function
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	MINUS    = "-"
 	BANG     = "!"
@@ -45,6 +82,7 @@ const (
 	RETURN   = "RETURN"
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	MACRO    = "MACRO"
 )
 
 var keywords = map[string]TokenType{
@@ -55,6 +93,7 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"macro":  MACRO,
 }
 
 func LookUpIdentifier(ident string) TokenType {
@@ -63,3 +102,13 @@ func LookUpIdentifier(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// Keywords returns every keyword of the language in alphabetical order
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
@@ -0,0 +1,505 @@
+package types
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/resolver"
+	"monkey/token"
+	"sort"
+)
+
+// Span is a range of source, from Pos up to End
+type Span struct {
+	Pos token.Position
+	End token.Position
+}
+
+func spanOf(n ast.Node) Span {
+	return Span{Pos: n.Pos(), End: n.End()}
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%s-%s", s.Pos, s.End)
+}
+
+// Error is a type error: two types that had to be equal and are not
+// Span is the expression that is checked, Spans are where the two types come from
+type Error struct {
+	Span  Span
+	Msg   string
+	Types [2]string // The mismatched types, as they are printed
+	Spans [2]Span
+}
+
+// Error formats the error as "line:column: message (from where the types come from)"
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (%s from %s, %s from %s)", e.Span.Pos, e.Msg, e.Types[0], e.Spans[0], e.Types[1], e.Spans[1])
+}
+
+// Info holds the result of checking a program
+type Info struct {
+	Types  map[ast.Expression]Type     // The type of every expression, with bound variables resolved
+	Defs   map[*ast.Identifier]*Scheme // The type of every let name and parameter
+	Errors []*Error                    // The errors in source order
+}
+
+// TypeOf returns the type of expr, or nil if it was not checked
+func (info *Info) TypeOf(expr ast.Expression) Type {
+	return info.Types[expr]
+}
+
+// Check infers the types of program
+// It uses the bindings of the resolver; an undefined name has a type of its own, the resolver reports it
+func Check(program *ast.Program) *Info {
+	c := &checker{
+		names:   resolver.Resolve(program),
+		schemes: map[*resolver.Symbol]*Scheme{},
+		forward: map[*resolver.Symbol]*Var{},
+		types:   map[ast.Expression]Type{},
+		info:    &Info{Defs: map[*ast.Identifier]*Scheme{}},
+	}
+	c.results = []Type{c.fresh()}
+	c.statements(program.Statements)
+	c.info.Types = make(map[ast.Expression]Type, len(c.types))
+	for expr, t := range c.types {
+		c.info.Types[expr] = resolve(t)
+	}
+	for _, s := range c.info.Defs {
+		s.Type = resolve(s.Type)
+	}
+	sort.SliceStable(c.info.Errors, func(i, j int) bool {
+		return c.info.Errors[i].Span.Pos.Offset < c.info.Errors[j].Span.Pos.Offset
+	})
+	return c.info
+}
+
+// checker holds the state of Check
+type checker struct {
+	names   *resolver.Info
+	schemes map[*resolver.Symbol]*Scheme // The current scheme of every symbol
+	forward map[*resolver.Symbol]*Var    // The types of the lets used by a function before they are declared
+	types   map[ast.Expression]Type
+	results []Type // The result types of the enclosing functions, the first one for the program
+	level   int
+	nextID  int
+	info    *Info
+}
+
+func (c *checker) fresh() *Var {
+	c.nextID++
+	return &Var{ID: c.nextID, level: c.level}
+}
+
+func basic(name string, origin ast.Node) *Basic {
+	return &Basic{Name: name, origin: origin}
+}
+
+// unify makes a and b equal, and reports an error at expr if they cannot be
+func (c *checker) unify(a, b Type, expr ast.Node) {
+	if !c.unifies(a, b) {
+		n := newNamer()
+		err := &Error{Span: spanOf(expr), Types: [2]string{n.name(a), n.name(b)}}
+		err.Msg = fmt.Sprintf("mismatched types %s and %s", err.Types[0], err.Types[1])
+		if _, ok := prune(a).(*Var); ok {
+			err.Msg = fmt.Sprintf("infinite type: %s would contain itself in %s", err.Types[0], err.Types[1])
+		} else if _, ok := prune(b).(*Var); ok {
+			err.Msg = fmt.Sprintf("infinite type: %s would contain itself in %s", err.Types[1], err.Types[0])
+		}
+		for i, t := range []Type{a, b} {
+			err.Spans[i] = err.Span
+			if o := origin(t); o != nil {
+				err.Spans[i] = spanOf(o)
+			}
+		}
+		c.info.Errors = append(c.info.Errors, err)
+	}
+}
+
+// unifies is a helper function that unifies a and b as far as it can and reports whether it succeeded
+func (c *checker) unifies(a, b Type) bool {
+	a, b = prune(a), prune(b)
+	if a == b {
+		return true
+	}
+	if v, ok := a.(*Var); ok {
+		return c.bind(v, b)
+	}
+	if v, ok := b.(*Var); ok {
+		return c.bind(v, a)
+	}
+	switch a := a.(type) {
+	case *Basic:
+		b, ok := b.(*Basic)
+		return ok && a.Name == b.Name
+	case *Func:
+		b, ok := b.(*Func)
+		if !ok || len(a.Params) != len(b.Params) {
+			return false
+		}
+		for i := range a.Params {
+			if !c.unifies(a.Params[i], b.Params[i]) {
+				return false
+			}
+		}
+		return c.unifies(a.Result, b.Result)
+	case *Con:
+		b, ok := b.(*Con)
+		if !ok || a.Name != b.Name || len(a.Args) != len(b.Args) {
+			return false
+		}
+		for i := range a.Args {
+			if !c.unifies(a.Args[i], b.Args[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// bind binds the free variable v to t, unless t contains v
+// The variables of t move to the level of v if it is lower, so they are not generalized too early
+func (c *checker) bind(v *Var, t Type) bool {
+	if occurs(v, t, v.level) {
+		return false
+	}
+	v.link = t
+	return true
+}
+
+// occurs is a helper function that reports whether v occurs in t, and lowers the levels of the
+// variables of t to level
+func occurs(v *Var, t Type, level int) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		if t == v {
+			return true
+		}
+		if t.level > level {
+			t.level = level
+		}
+	case *Func:
+		for _, p := range t.Params {
+			if occurs(v, p, level) {
+				return true
+			}
+		}
+		return occurs(v, t.Result, level)
+	case *Con:
+		for _, a := range t.Args {
+			if occurs(v, a, level) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// generalize returns the scheme of t over the variables created deeper than the current level
+func (c *checker) generalize(t Type) *Scheme {
+	s := &Scheme{Type: t}
+	seen := map[*Var]bool{}
+	var collect func(Type)
+	collect = func(t Type) {
+		switch t := prune(t).(type) {
+		case *Var:
+			if t.level > c.level && !seen[t] {
+				seen[t] = true
+				s.Vars = append(s.Vars, t)
+			}
+		case *Func:
+			for _, p := range t.Params {
+				collect(p)
+			}
+			collect(t.Result)
+		case *Con:
+			for _, a := range t.Args {
+				collect(a)
+			}
+		}
+	}
+	collect(t)
+	return s
+}
+
+// instantiate returns the type of s with fresh variables for the generalized ones
+func (c *checker) instantiate(s *Scheme) Type {
+	if len(s.Vars) == 0 {
+		return s.Type
+	}
+	fresh := map[*Var]Type{}
+	for _, v := range s.Vars {
+		fresh[v] = c.fresh()
+	}
+	var copyType func(Type) Type
+	copyType = func(t Type) Type {
+		switch t := prune(t).(type) {
+		case *Var:
+			if f, ok := fresh[t]; ok {
+				return f
+			}
+			return t
+		case *Func:
+			params := make([]Type, len(t.Params))
+			for i, p := range t.Params {
+				params[i] = copyType(p)
+			}
+			return &Func{Params: params, Result: copyType(t.Result), origin: t.origin}
+		case *Con:
+			args := make([]Type, len(t.Args))
+			for i, a := range t.Args {
+				args[i] = copyType(a)
+			}
+			return &Con{Name: t.Name, Args: args, origin: t.origin}
+		default:
+			return t
+		}
+	}
+	return copyType(s.Type)
+}
+
+func (c *checker) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		c.statement(stmt)
+	}
+}
+
+func (c *checker) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		c.let(s)
+	case *ast.ReturnStatement:
+		if s.ReturnValue != nil {
+			c.unify(c.results[len(c.results)-1], c.expression(s.ReturnValue), s.ReturnValue)
+		}
+	case *ast.ExpressionStatement:
+		if s.Expression != nil {
+			c.expression(s.Expression)
+		}
+	case *ast.BlockStatement:
+		c.block(s)
+	}
+}
+
+// let checks a let statement and binds the generalized type of its value to its name
+func (c *checker) let(s *ast.LetStatement) {
+	var sym *resolver.Symbol
+	if s.Name != nil {
+		sym = c.names.Defs[s.Name]
+	}
+	c.level++
+	// A function can call itself, so its name is bound while the function is checked
+	var self *Var
+	if _, ok := s.Value.(*ast.FunctionLiteral); ok && sym != nil {
+		self = c.fresh()
+		c.schemes[sym] = &Scheme{Type: self}
+	}
+	var t Type = c.fresh()
+	if s.Value != nil {
+		t = c.expression(s.Value)
+	}
+	if self != nil {
+		c.unify(self, t, s.Value)
+	}
+	if s.Type != nil && s.Value != nil {
+		c.unify(c.annotation(s.Type), t, s.Value)
+	}
+	c.level--
+	if sym == nil {
+		return
+	}
+	if v, ok := c.forward[sym]; ok && s.Name == sym.Decl {
+		// A function used the name before this let, with a type that cannot be generalized any more
+		c.unify(v, t, s.Value)
+	}
+	scheme := c.generalize(t)
+	c.schemes[sym] = scheme
+	c.info.Defs[s.Name] = scheme
+	c.types[s.Name] = t
+}
+
+// block returns the type of the value of a block, the value of its last statement
+// A block that ends with a return has no value of its own, so its type is a free variable
+func (c *checker) block(b *ast.BlockStatement) Type {
+	c.statements(b.Statements)
+	if len(b.Statements) == 0 {
+		return basic(Null, b)
+	}
+	switch last := b.Statements[len(b.Statements)-1].(type) {
+	case *ast.ExpressionStatement:
+		if t, ok := c.types[last.Expression]; ok {
+			return t
+		}
+	case *ast.ReturnStatement:
+		return c.fresh()
+	}
+	return basic(Null, b)
+}
+
+// expression returns the type of expr and records it
+func (c *checker) expression(expr ast.Expression) Type {
+	t := c.infer(expr)
+	c.types[expr] = t
+	return t
+}
+
+func (c *checker) infer(expr ast.Expression) Type {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return basic(Int, e)
+	case *ast.Boolean:
+		return basic(Bool, e)
+	case *ast.Identifier:
+		return c.identifier(e)
+	case *ast.PrefixExpression:
+		if e.Right == nil {
+			return c.fresh()
+		}
+		right := c.expression(e.Right)
+		switch e.Operator {
+		case "-":
+			c.unify(right, basic(Int, e), e.Right)
+			return basic(Int, e)
+		case "!":
+			return basic(Bool, e)
+		}
+		return c.fresh()
+	case *ast.InfixExpression:
+		return c.infix(e)
+	case *ast.IfExpression:
+		if e.Condition != nil {
+			c.expression(e.Condition)
+		}
+		var consequence Type = c.fresh()
+		if e.Consequence != nil {
+			consequence = c.block(e.Consequence)
+		}
+		if e.Alternative == nil {
+			// The value is null when the condition does not hold, the consequence is only run for its effects
+			return basic(Null, e)
+		}
+		c.unify(consequence, c.block(e.Alternative), e)
+		return consequence
+	case *ast.FunctionLiteral:
+		return c.function(e)
+	case *ast.CallExpression:
+		if e.Function == nil {
+			return c.fresh()
+		}
+		fn := c.expression(e.Function)
+		call := &Func{Result: c.fresh(), origin: e}
+		for _, arg := range e.Arguments {
+			var t Type = c.fresh()
+			if arg != nil {
+				t = c.expression(arg)
+			}
+			call.Params = append(call.Params, t)
+		}
+		c.unify(fn, call, e)
+		return call.Result
+	}
+	return c.fresh()
+}
+
+// identifier returns the type of a use of a name
+func (c *checker) identifier(id *ast.Identifier) Type {
+	sym := c.names.SymbolOf(id)
+	if sym == nil {
+		return c.fresh()
+	}
+	if s, ok := c.schemes[sym]; ok {
+		return c.instantiate(s)
+	}
+	// A use in a function of a let that comes after the function
+	v, ok := c.forward[sym]
+	if !ok {
+		v = &Var{ID: c.fresh().ID} // At level 0, so it is never generalized
+		c.forward[sym] = v
+	}
+	return v
+}
+
+func (c *checker) infix(e *ast.InfixExpression) Type {
+	var left, right Type = c.fresh(), c.fresh()
+	if e.Left != nil {
+		left = c.expression(e.Left)
+	}
+	if e.Right != nil {
+		right = c.expression(e.Right)
+	}
+	switch e.Operator {
+	case "+", "-", "*", "/":
+		c.unify(left, basic(Int, e), e.Left)
+		c.unify(right, basic(Int, e), e.Right)
+		return basic(Int, e)
+	case "<", ">":
+		c.unify(left, basic(Int, e), e.Left)
+		c.unify(right, basic(Int, e), e.Right)
+		return basic(Bool, e)
+	case "==", "!=":
+		c.unify(right, left, e)
+		return basic(Bool, e)
+	}
+	return c.fresh()
+}
+
+func (c *checker) function(fn *ast.FunctionLiteral) Type {
+	t := &Func{Result: c.fresh(), origin: fn}
+	if fn.ReturnType != nil {
+		t.Result = c.annotation(fn.ReturnType)
+	}
+	for _, p := range fn.Parameters {
+		var v Type = c.fresh()
+		if p != nil && p.Type != nil {
+			v = c.annotation(p.Type)
+		}
+		t.Params = append(t.Params, v)
+		if p == nil {
+			continue
+		}
+		if sym := c.names.Defs[p]; sym != nil && sym.Decl == p {
+			c.schemes[sym] = &Scheme{Type: v}
+		}
+		c.info.Defs[p] = &Scheme{Type: v}
+		c.types[p] = v
+	}
+	if fn.Body != nil {
+		c.results = append(c.results, t.Result)
+		body := c.block(fn.Body)
+		c.results = c.results[:len(c.results)-1]
+		c.unify(t.Result, body, fn.Body)
+	}
+	return t
+}
+
+// annotation returns the type a type annotation names
+// A name without arguments is a basic type, so a misspelt name only matches itself
+func (c *checker) annotation(te ast.TypeExpr) Type {
+	switch te := te.(type) {
+	case *ast.NamedType:
+		if len(te.Args) == 0 {
+			return basic(te.Name, te)
+		}
+		return &Con{Name: te.Name, Args: c.annotations(te.Args), origin: te}
+	case *ast.ListType:
+		if te.Elem == nil {
+			return &Con{Name: List, Args: []Type{c.fresh()}, origin: te}
+		}
+		return &Con{Name: List, Args: []Type{c.annotation(te.Elem)}, origin: te}
+	case *ast.FunctionType:
+		t := &Func{Params: c.annotations(te.Params), Result: c.fresh(), origin: te}
+		if te.Result != nil {
+			t.Result = c.annotation(te.Result)
+		}
+		return t
+	}
+	return c.fresh()
+}
+
+func (c *checker) annotations(tes []ast.TypeExpr) []Type {
+	types := make([]Type, len(tes))
+	for i, te := range tes {
+		types[i] = c.annotation(te)
+	}
+	return types
+}
@@ -0,0 +1,147 @@
+package types
+
+import (
+	"monkey/ast"
+	"monkey/parser"
+	"reflect"
+	"testing"
+)
+
+func check(t *testing.T, src string) (*ast.Program, *Info) {
+	t.Helper()
+	program, err := parser.ParseFile("", src, 0)
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	return program, Check(program)
+}
+
+// lets returns the schemes of the let statements at the top level of program, by name
+// A name let twice gets the scheme of its last let
+func lets(program *ast.Program, info *Info) map[string]string {
+	schemes := map[string]string{}
+	for _, stmt := range program.Statements {
+		if ls, ok := stmt.(*ast.LetStatement); ok {
+			schemes[ls.Name.Value] = info.Defs[ls.Name].String()
+		}
+	}
+	return schemes
+}
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		src  string
+		want map[string]string
+	}{
+		{"let a = 1; let b = !a; let c = a < 2;", map[string]string{"a": "int", "b": "bool", "c": "bool"}},
+		{"let n = if (true) { 1 };", map[string]string{"n": "null"}},
+		{"let n = if (true) { 1 } else { 2 };", map[string]string{"n": "int"}},
+		// Any value can be a condition, so an int is as good as a bool
+		{"let n = if (1) { true } else { false };", map[string]string{"n": "bool"}},
+		{"let add = fn(a, b) { a + b };", map[string]string{"add": "fn(int, int) -> int"}},
+		{"let eq = fn(a, b) { a == b };", map[string]string{"eq": "fn('a, 'a) -> bool"}},
+		{"let k = fn(x, y) { x };", map[string]string{"k": "fn('a, 'b) -> 'a"}},
+		{"let apply = fn(f, x) { f(x) };", map[string]string{"apply": "fn(fn('a) -> 'b, 'a) -> 'b"}},
+		{"let compose = fn(f, g) { fn(x) { f(g(x)) } };", map[string]string{"compose": "fn(fn('a) -> 'b, fn('c) -> 'a) -> fn('c) -> 'b"}},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };", map[string]string{"fact": "fn(int) -> int"}},
+		{"let f = fn(x) { if (x) { return 1; } 2 };", map[string]string{"f": "fn('a) -> int"}},
+		// A function can use a later let
+		{"let f = fn() { g(1) }; let g = fn(x) { x < 2 };", map[string]string{"f": "fn() -> bool", "g": "fn(int) -> bool"}},
+		// Annotations constrain what they annotate
+		{"let f = fn(x: int) { x };", map[string]string{"f": "fn(int) -> int"}},
+		{"let f = fn(x) -> bool { x };", map[string]string{"f": "fn(bool) -> bool"}},
+		{"let f = fn(l: [int], g: fn(int) -> bool) { g };", map[string]string{"f": "fn([int], fn(int) -> bool) -> fn(int) -> bool"}},
+		{"let f = fn(m: map[int, a]) { m };", map[string]string{"f": "fn(map[int, a]) -> map[int, a]"}},
+	}
+	for _, tt := range tests {
+		program, info := check(t, tt.src)
+		if len(info.Errors) > 0 {
+			t.Errorf("%q: unexpected error %v", tt.src, info.Errors[0])
+			continue
+		}
+		if got := lets(program, info); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q:\ngot  %v\nwant %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestLetPolymorphism checks that a function bound by let can be used at different types, while
+// a parameter, which is not generalized, cannot
+func TestLetPolymorphism(t *testing.T) {
+	program, info := check(t, `
+let id = fn(x) { x };
+let a = id(1);
+let b = id(true);
+let c = id(id)(2);
+let twice = fn(f, x) { f(f(x)) };
+let d = twice(fn(n) { n + 1 }, 1);
+let e = twice(fn(b) { !b }, true);
+`)
+	if len(info.Errors) > 0 {
+		t.Fatalf("unexpected error %v", info.Errors[0])
+	}
+	want := map[string]string{
+		"id":    "fn('a) -> 'a",
+		"a":     "int",
+		"b":     "bool",
+		"c":     "int",
+		"twice": "fn(fn('a) -> 'a, 'a) -> 'a",
+		"d":     "int",
+		"e":     "bool",
+	}
+	if got := lets(program, info); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+
+	// The type of every use of id is an instance of its scheme
+	uses := map[string]bool{}
+	ast.Inspect(program, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpression); ok {
+			if id, ok := call.Function.(*ast.Identifier); ok && id.Value == "id" {
+				uses[info.TypeOf(id).String()] = true
+			}
+		}
+		return n != nil
+	})
+	wantUses := map[string]bool{"fn(int) -> int": true, "fn(bool) -> bool": true, "fn(fn(int) -> int) -> fn(int) -> int": true}
+	if !reflect.DeepEqual(uses, wantUses) {
+		t.Errorf("the uses of id have the types %v, want %v", uses, wantUses)
+	}
+
+	// A parameter has one type in the body of its function
+	_, info = check(t, "let f = fn(g) { g(1); g(true) };")
+	if len(info.Errors) != 1 || info.Errors[0].Msg != "mismatched types fn(int) -> 'a and fn(bool) -> 'b" {
+		t.Errorf("using a parameter at two types: got %v, want a mismatch", info.Errors)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"1 + true;", []string{"1:5: mismatched types bool and int (bool from 1:5-1:9, int from 1:1-1:9)"}},
+		{"-true;", []string{"1:2: mismatched types bool and int (bool from 1:2-1:6, int from 1:1-1:6)"}},
+		{"if (true) { 1 } else { false };", []string{"1:1: mismatched types int and bool (int from 1:13-1:14, bool from 1:24-1:29)"}},
+		{"let f = fn(x) { x }; f(1, 2);", []string{"1:22: mismatched types fn('a) -> 'a and fn(int, int) -> 'b (fn('a) -> 'a from 1:9-1:20, fn(int, int) -> 'b from 1:22-1:29)"}},
+		{"1(2);", []string{"1:1: mismatched types int and fn(int) -> 'a (int from 1:1-1:2, fn(int) -> 'a from 1:1-1:5)"}},
+		{"let self = fn(f) { f(f) };", []string{"1:20: infinite type: 'a would contain itself in fn('a) -> 'b ('a from 1:20-1:24, fn('a) -> 'b from 1:20-1:24)"}},
+		{"let l: [int] = 1;", []string{"1:16: mismatched types [int] and int ([int] from 1:8-1:13, int from 1:16-1:17)"}},
+		{"let f = fn(x: int) -> bool { x };", []string{"1:28: mismatched types bool and int (bool from 1:23-1:27, int from 1:15-1:18)"}},
+		// Every error is reported, in source order
+		{"let a = 1 + true; let b = !a + 1;", []string{
+			"1:13: mismatched types bool and int (bool from 1:13-1:17, int from 1:9-1:17)",
+			"1:27: mismatched types bool and int (bool from 1:27-1:29, int from 1:27-1:33)",
+		}},
+	}
+	for _, tt := range tests {
+		_, info := check(t, tt.src)
+		var got []string
+		for _, err := range info.Errors {
+			got = append(got, err.Error())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q:\ngot  %q\nwant %q", tt.src, got, tt.want)
+		}
+	}
+}
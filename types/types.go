@@ -0,0 +1,174 @@
+// Package types infers the types of Monkey programs
+//
+// The checker follows Hindley–Milner: every expression gets a type, type variables stand for the
+// types that are not known yet, and unifying two types makes them equal or reports a mismatch.
+// A let statement generalizes the type of its value, so a function bound by let can be used at
+// different types. The types are int, bool, null, which is the value of an if without else,
+// and functions. Any value can be a condition, since the language treats false and null as false
+// and every other value as true. Type annotations add the types they name, such as lists and
+// generic types, and constrain what they annotate
+package types
+
+import (
+	"fmt"
+	"monkey/ast"
+	"strings"
+)
+
+// Type is the type of a value: a *Basic, a *Con, a *Func or a *Var
+type Type interface {
+	String() string
+	typeNode()
+}
+
+// Basic is one of the types int, bool and null
+type Basic struct {
+	Name   string
+	origin ast.Node // The expression the type was first required or produced by
+}
+
+// Con is a type with type arguments, such as map[string, int], or a list such as [int]
+// No expression has such a type yet, only annotations name them
+type Con struct {
+	Name   string // The name of the type, "[]" for a list
+	Args   []Type
+	origin ast.Node
+}
+
+// The name of list types
+const List = "[]"
+
+// Func is the type of a function
+type Func struct {
+	Params []Type
+	Result Type
+	origin ast.Node
+}
+
+// Var is a type variable. Once it is bound it stands for the type it is bound to
+type Var struct {
+	ID    int
+	level int  // The let nesting level the variable was created at, for generalization
+	link  Type // The type the variable is bound to, nil while it is free
+}
+
+func (b *Basic) typeNode() {}
+func (c *Con) typeNode()   {}
+func (f *Func) typeNode()  {}
+func (v *Var) typeNode()   {}
+
+func (b *Basic) String() string { return b.Name }
+func (c *Con) String() string   { return newNamer().name(c) }
+func (f *Func) String() string  { return newNamer().name(f) }
+func (v *Var) String() string   { return newNamer().name(v) }
+
+// The names of the basic types
+const (
+	Int  = "int"
+	Bool = "bool"
+	Null = "null"
+)
+
+// Scheme is a type generalized over some of its variables, such as fn('a) -> 'a
+// Every use of a let binding instantiates the scheme of its value with fresh variables
+type Scheme struct {
+	Vars []*Var // The generalized variables
+	Type Type
+}
+
+func (s *Scheme) String() string {
+	return newNamer().name(s.Type)
+}
+
+// prune is a helper function that follows the links of bound variables
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.link == nil {
+			return t
+		}
+		t = v.link
+	}
+}
+
+// resolve returns t with every bound variable replaced by its type
+func resolve(t Type) Type {
+	switch t := prune(t).(type) {
+	case *Func:
+		params := make([]Type, len(t.Params))
+		for i, p := range t.Params {
+			params[i] = resolve(p)
+		}
+		return &Func{Params: params, Result: resolve(t.Result), origin: t.origin}
+	case *Con:
+		args := make([]Type, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = resolve(a)
+		}
+		return &Con{Name: t.Name, Args: args, origin: t.origin}
+	default:
+		return t
+	}
+}
+
+// origin is a helper function that returns the expression a type comes from, nil for a variable
+func origin(t Type) ast.Node {
+	switch t := prune(t).(type) {
+	case *Basic:
+		return t.origin
+	case *Con:
+		return t.origin
+	case *Func:
+		return t.origin
+	}
+	return nil
+}
+
+// namer names the free variables of types 'a, 'b and so on, in the order it meets them
+// Types printed with the same namer share the names
+type namer struct {
+	names map[*Var]string
+}
+
+func newNamer() *namer {
+	return &namer{names: map[*Var]string{}}
+}
+
+func (n *namer) name(t Type) string {
+	switch t := prune(t).(type) {
+	case *Basic:
+		return t.Name
+	case *Func:
+		params := make([]string, len(t.Params))
+		for i, p := range t.Params {
+			params[i] = n.name(p)
+		}
+		return fmt.Sprintf("fn(%s) -> %s", strings.Join(params, ", "), n.name(t.Result))
+	case *Con:
+		args := make([]string, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = n.name(a)
+		}
+		if t.Name == List && len(args) == 1 {
+			return "[" + args[0] + "]"
+		}
+		return fmt.Sprintf("%s[%s]", t.Name, strings.Join(args, ", "))
+	case *Var:
+		name, ok := n.names[t]
+		if !ok {
+			name = varName(len(n.names))
+			n.names[t] = name
+		}
+		return name
+	}
+	return "?"
+}
+
+// varName is a helper function that returns the name of the i-th variable: 'a to 'z, then 'a1 and so on
+func varName(i int) string {
+	name := "'" + string(rune('a'+i%26))
+	if i >= 26 {
+		name += fmt.Sprint(i / 26)
+	}
+	return name
+}
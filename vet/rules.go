@@ -0,0 +1,146 @@
+package vet
+
+import (
+	"monkey/ast"
+	"monkey/resolver"
+	"strings"
+)
+
+var constCondRule = &Rule{
+	Name: "constcond",
+	Doc:  "report if expressions whose condition is a constant",
+	Run: func(p *Pass) {
+		ast.Inspect(p.Program, func(n ast.Node) bool {
+			if ie, ok := n.(*ast.IfExpression); ok && ie.Condition != nil && isConstant(ie.Condition) {
+				p.Report(ie.Condition, "condition %s is constant", ie.Condition)
+			}
+			return n != nil
+		})
+	},
+}
+
+var divZeroRule = &Rule{
+	Name: "divzero",
+	Doc:  "report divisions by the literal zero",
+	Run: func(p *Pass) {
+		ast.Inspect(p.Program, func(n ast.Node) bool {
+			if ie, ok := n.(*ast.InfixExpression); ok && ie.Operator == "/" && isZero(ie.Right) {
+				p.Report(ie, "division by zero")
+			}
+			return n != nil
+		})
+	},
+}
+
+var selfCompareRule = &Rule{
+	Name: "selfcompare",
+	Doc:  "report comparisons of an expression with itself",
+	Run: func(p *Pass) {
+		ast.Inspect(p.Program, func(n ast.Node) bool {
+			ie, ok := n.(*ast.InfixExpression)
+			if !ok || ie.Left == nil || ie.Right == nil || !isPure(ie.Left) || !ast.Equal(ie.Left, ie.Right, ast.IgnorePositions) {
+				return n != nil
+			}
+			switch ie.Operator {
+			case "==":
+				p.Report(ie, "%s is always true", ie)
+			case "!=", "<", ">":
+				p.Report(ie, "%s is always false", ie)
+			}
+			return true
+		})
+	},
+}
+
+var shadowRule = &Rule{
+	Name: "shadow",
+	Doc:  "report declarations that hide a binding of an enclosing scope",
+	Run: func(p *Pass) {
+		for _, problem := range p.Info.Problems {
+			if problem.Kind == resolver.Shadowing {
+				p.Report(problem.Ident, "%s", problem.Msg)
+			}
+		}
+	},
+}
+
+var unreachableRule = &Rule{
+	Name: "unreachable",
+	Doc:  "report statements after a return in a block",
+	Run: func(p *Pass) {
+		ast.Inspect(p.Program, func(n ast.Node) bool {
+			if b, ok := n.(*ast.BlockStatement); ok {
+				for i := 0; i < len(b.Statements)-1; i++ {
+					if _, ok := b.Statements[i].(*ast.ReturnStatement); ok {
+						rest := b.Statements[i+1:]
+						p.ReportRange(rest[0].Pos(), rest[len(rest)-1].End(), "unreachable code")
+						break
+					}
+				}
+			}
+			return n != nil
+		})
+	},
+}
+
+var unusedRule = &Rule{
+	Name: "unused",
+	Doc:  "report let bindings and parameters that are never used; names starting with _ are left out",
+	Run: func(p *Pass) {
+		var visit func(*resolver.Scope)
+		visit = func(s *resolver.Scope) {
+			for _, sym := range s.Symbols {
+				if len(sym.Uses) > 0 || strings.HasPrefix(sym.Name, "_") {
+					continue
+				}
+				if sym.Kind == resolver.ParamSymbol {
+					p.Report(sym.Decl, "parameter %s is not used", sym.Name)
+				} else {
+					p.Report(sym.Decl, "%s is declared and not used", sym.Name)
+				}
+			}
+			for _, child := range s.Children {
+				visit(child)
+			}
+		}
+		visit(p.Info.Scope)
+	},
+}
+
+// isConstant is a helper function that reports whether expr is made of literals and operators only
+func isConstant(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		return e.Right != nil && isConstant(e.Right)
+	case *ast.InfixExpression:
+		return e.Left != nil && e.Right != nil && isConstant(e.Left) && isConstant(e.Right)
+	}
+	return false
+}
+
+// isZero is a helper function that reports whether expr is the literal 0, possibly negated
+func isZero(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return e.Value == 0
+	case *ast.PrefixExpression:
+		return e.Operator == "-" && isZero(e.Right)
+	}
+	return false
+}
+
+// isPure is a helper function that reports whether evaluating expr twice gives the same value
+// Calls may have side effects, and two evaluations of a function literal give different functions
+func isPure(expr ast.Expression) bool {
+	pure := true
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.CallExpression, *ast.FunctionLiteral:
+			pure = false
+		}
+		return pure && n != nil
+	})
+	return pure
+}
@@ -0,0 +1,25 @@
+// A comment trailing code suppresses the findings on its own line
+let a = 1; // vet:ignore unused
+let b = 2; // want unused
+
+// A comment on a line of its own suppresses the findings on the next line
+// vet:ignore unused
+let c = 3;
+// vet:ignore shadow
+let d = 3; // want unused
+// vet:ignore
+let e = 1 / 0;
+let f = 1 / 0; // want divzero unused
+
+// The rules can be separated by commas or spaces, and all of them can be left out
+let g = 1 / 0; // vet:ignore divzero,unused
+let h = 1 / 0; // vet:ignore divzero unused
+let i = 1 / 0; // vet:ignore
+
+// A comment with something else glued to the prefix is not an ignore
+// vet:ignored
+let j = 4; // want unused
+
+// Two comments for the same line add up
+// vet:ignore divzero
+let k = 1 / 0; // vet:ignore unused
@@ -0,0 +1,36 @@
+// Every line with findings says which rules report them
+let used = 1;
+let unused = 2; // want unused
+let _ignored = 3;
+
+let f = fn(a, b) { // want unused
+	a
+};
+f(used, 0);
+
+if (true) { 1 }; // want constcond
+if (1 + 2 > 3) { 1 }; // want constcond
+if (used) { 1 };
+
+used / 0; // want divzero
+used / -0; // want divzero
+used / 1;
+
+used == used; // want selfcompare
+used < used; // want selfcompare
+f(1) == f(1);
+fn() { 1 } == fn() { 1 };
+
+let g = fn(used) { // want shadow
+	if (used) {
+		let used = 1; // want shadow
+		return used;
+		used + 1; // want unreachable
+		used
+	}
+};
+g(1);
+
+// Two rules can report the same expression
+if (used == used) { 1 }; // want selfcompare
+if (1 / 0 == 1 / 0) { 1 }; // want constcond divzero divzero selfcompare
@@ -0,0 +1,163 @@
+// Package vet reports suspicious constructs in Monkey programs
+//
+// Every check is a Rule with a name, which is the ID its findings carry. Check runs a set of rules
+// over a program, and a tool can toggle the rules by name or add its own. A comment of the form
+//
+//	// vet:ignore rule1 rule2
+//
+// suppresses the findings of the named rules on its line when it trails code, and on the next line
+// when it stands on a line of its own. Without rule names it suppresses them all
+package vet
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/resolver"
+	"monkey/token"
+	"sort"
+	"strings"
+)
+
+// Finding is a problem reported by a rule
+type Finding struct {
+	Rule string         // The name of the rule
+	Pos  token.Position // The start of the offending code
+	End  token.Position // The position just after the offending code
+	Msg  string
+}
+
+// String formats the finding as "line:column: message (rule)"
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s (%s)", f.Pos, f.Msg, f.Rule)
+}
+
+// Rule is a check. Run inspects the program of the pass and reports its findings to it
+type Rule struct {
+	Name string // The rule ID, a single lower case word
+	Doc  string // A one line description
+	Run  func(*Pass)
+}
+
+// Pass is what a rule works on
+type Pass struct {
+	Program *ast.Program
+	Info    *resolver.Info // The symbol table of the program
+	rule    *Rule
+	report  func(Finding)
+}
+
+// Report reports a finding of the current rule that spans node
+func (p *Pass) Report(node ast.Node, format string, args ...interface{}) {
+	p.ReportRange(node.Pos(), node.End(), format, args...)
+}
+
+// ReportRange reports a finding of the current rule that spans the source from pos up to end
+func (p *Pass) ReportRange(pos, end token.Position, format string, args ...interface{}) {
+	p.report(Finding{Rule: p.rule.Name, Pos: pos, End: end, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Rules returns every built in rule, in alphabetical order
+func Rules() []*Rule {
+	return []*Rule{constCondRule, divZeroRule, selfCompareRule, shadowRule, unreachableRule, unusedRule}
+}
+
+// Lookup returns the built in rule with the given name, or nil
+func Lookup(name string) *Rule {
+	for _, r := range Rules() {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Check runs the rules over program and returns the findings that no vet:ignore comment suppresses
+// The findings are sorted by position. The comments of program must have been kept by the parser
+func Check(program *ast.Program, rules []*Rule) []Finding {
+	ignores := ignoreComments(program)
+	var findings []Finding
+	pass := &Pass{Program: program, Info: resolver.Resolve(program)}
+	pass.report = func(f Finding) {
+		if !ignores.suppress(f) {
+			findings = append(findings, f)
+		}
+	}
+	for _, rule := range rules {
+		pass.rule = rule
+		rule.Run(pass)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Pos.Offset != findings[j].Pos.Offset {
+			return findings[i].Pos.Offset < findings[j].Pos.Offset
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+	return findings
+}
+
+// ignores maps a line to the rules ignored on it; an empty list ignores every rule
+type ignores map[int][]string
+
+const ignorePrefix = "vet:ignore"
+
+// ignoreComments is a helper function that collects the vet:ignore comments of program
+func ignoreComments(program *ast.Program) ignores {
+	ig := ignores{}
+	if len(program.Comments) == 0 {
+		return ig
+	}
+	// The offset of the first node boundary of every line, to tell trailing comments apart
+	first := map[int]int{}
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		for _, pos := range []token.Position{n.Pos(), n.End()} {
+			if off, ok := first[pos.Line]; !ok || pos.Offset < off {
+				first[pos.Line] = pos.Offset
+			}
+		}
+		return true
+	})
+	for _, c := range program.Comments {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Literal, "//"))
+		if !strings.HasPrefix(text, ignorePrefix) {
+			continue
+		}
+		rest := text[len(ignorePrefix):]
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' && rest[0] != ',' {
+			continue
+		}
+		rules := strings.FieldsFunc(rest, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' })
+		if rules == nil {
+			rules = []string{}
+		}
+		line := c.Pos.Line + 1
+		if off, ok := first[c.Pos.Line]; ok && off < c.Pos.Offset {
+			line = c.Pos.Line
+		}
+		if old, ok := ig[line]; ok && (len(old) == 0 || len(rules) == 0) {
+			ig[line] = []string{}
+		} else {
+			ig[line] = append(old, rules...)
+		}
+	}
+	return ig
+}
+
+// suppress reports whether a comment ignores the finding
+func (ig ignores) suppress(f Finding) bool {
+	rules, ok := ig[f.Pos.Line]
+	if !ok {
+		return false
+	}
+	if len(rules) == 0 {
+		return true
+	}
+	for _, r := range rules {
+		if r == f.Rule {
+			return true
+		}
+	}
+	return false
+}
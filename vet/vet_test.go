@@ -0,0 +1,121 @@
+package vet
+
+import (
+	"io/ioutil"
+	"monkey/parser"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// wants returns the rules that the "// want rule..." comments of src expect to report, by line
+func wants(t *testing.T, src []byte) map[int][]string {
+	program, err := parser.ParseFile("", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int][]string{}
+	for _, c := range program.Comments {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Literal, "//"))
+		if strings.HasPrefix(text, "want ") {
+			rules := strings.Fields(text[len("want "):])
+			sort.Strings(rules)
+			want[c.Pos.Line] = rules
+		}
+	}
+	return want
+}
+
+// TestCheck runs every rule over the files of testdata, and compares the findings on every line
+// with the rules its want comment names
+func TestCheck(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.mk"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no test files: %v", err)
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		program, err := parser.ParseFile(file, src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[int][]string{}
+		for _, f := range Check(program, Rules()) {
+			got[f.Pos.Line] = append(got[f.Pos.Line], f.Rule)
+		}
+		for _, rules := range got {
+			sort.Strings(rules)
+		}
+		want := wants(t, src)
+		for line := 1; line <= strings.Count(string(src), "\n")+1; line++ {
+			if !reflect.DeepEqual(got[line], want[line]) {
+				t.Errorf("%s:%d: got findings %v, want %v", file, line, got[line], want[line])
+			}
+		}
+	}
+}
+
+func TestFindings(t *testing.T) {
+	src := "let f = fn(x) {\n\treturn 1;\n\tx;\n\t2\n};\nf(1 / 0);\n"
+	program, err := parser.ParseFile("", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range Check(program, Rules()) {
+		got = append(got, f.String()+" until "+f.End.String())
+	}
+	want := []string{
+		"3:2: unreachable code (unreachable) until 4:3",
+		"6:3: division by zero (divzero) until 6:8",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	// Only the rules asked for run
+	findings := Check(program, []*Rule{Lookup("divzero")})
+	if len(findings) != 1 || findings[0].Rule != "divzero" {
+		t.Errorf("Check with divzero only: got %v", findings)
+	}
+}
+
+func TestRules(t *testing.T) {
+	var names []string
+	for _, r := range Rules() {
+		names = append(names, r.Name)
+		if Lookup(r.Name) != r {
+			t.Errorf("Lookup(%q) does not return the rule", r.Name)
+		}
+		if r.Doc == "" || strings.ToLower(r.Name) != r.Name || strings.ContainsAny(r.Name, " ,") {
+			t.Errorf("rule %q needs a lower case name of one word and a doc", r.Name)
+		}
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("Rules() = %v, want them in alphabetical order", names)
+	}
+	if Lookup("nope") != nil {
+		t.Errorf("Lookup of an unknown rule is not nil")
+	}
+}
+
+func TestCustomRule(t *testing.T) {
+	program, err := parser.ParseFile("", "let a = 1; // vet:ignore custom\nlet b = 2;\n", parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := &Rule{Name: "custom", Doc: "report every let statement", Run: func(p *Pass) {
+		for _, stmt := range p.Program.Statements {
+			p.Report(stmt, "a let")
+		}
+	}}
+	findings := Check(program, []*Rule{custom})
+	if len(findings) != 1 || findings[0].String() != "2:1: a let (custom)" {
+		t.Errorf("got %v, want the let on line 2 only", findings)
+	}
+}